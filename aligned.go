@@ -0,0 +1,120 @@
+package chunk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// sqliteMagic is the header string that starts every SQLite database file.
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+// ErrPageSize is returned by NewAlignedSplitter when no page size is given
+// and none can be detected from the input.
+var ErrPageSize = errors.New("page size not given and not detectable from the input")
+
+// AlignedSplitter implements the Splitter interface and produces chunks
+// that are aligned to the pages of a database or the clusters of a disk
+// image. An optional header of fixed size is emitted first, and every
+// following chunk covers the same number of whole pages, so that pages that
+// did not change between two snapshots produce identical chunks.
+type AlignedSplitter struct {
+	r      io.Reader
+	trap   *readTrap
+	header Splitter
+	pages  Splitter
+
+	pageSize int64
+}
+
+// NewAlignedSplitter returns an AlignedSplitter producing chunks of
+// pagesPerChunk pages of pageSize bytes after the first headerSkip bytes.
+// If pageSize is 0, it is read from the header of an SQLite database. If
+// pagesPerChunk is 0, as many pages as fit into DefaultBlockSize are used.
+func NewAlignedSplitter(r io.Reader, pageSize, pagesPerChunk, headerSkip int64) (*AlignedSplitter, error) {
+	trap := newReadTrap(r)
+	br := bufio.NewReader(trap)
+
+	if pageSize == 0 {
+		pageSize = sqlitePageSize(br)
+		if trap.err != nil {
+			return nil, trap.err
+		} else if pageSize == 0 {
+			return nil, ErrPageSize
+		}
+	}
+
+	if pagesPerChunk == 0 && pageSize > 0 {
+		pagesPerChunk = DefaultBlockSize / pageSize
+		if pagesPerChunk == 0 {
+			pagesPerChunk = 1
+		}
+	}
+
+	if pageSize < 0 || pagesPerChunk <= 0 || headerSkip < 0 {
+		return nil, ErrSize
+	} else if pagesPerChunk > int64(ChunkSizeLimit)/pageSize {
+		return nil, ErrSizeMax
+	}
+
+	as := &AlignedSplitter{
+		r:        r,
+		trap:     trap,
+		pages:    NewSizeSplitter(br, pageSize*pagesPerChunk),
+		pageSize: pageSize,
+	}
+	if headerSkip > 0 {
+		size := headerSkip
+		if size > int64(ChunkSizeLimit) {
+			size = int64(ChunkSizeLimit)
+		}
+		as.header = NewSizeSplitter(io.LimitReader(br, headerSkip), size)
+	}
+	return as, nil
+}
+
+// sqlitePageSize returns the page size of the SQLite database in br, or 0
+// if br does not hold one.
+func sqlitePageSize(br *bufio.Reader) int64 {
+	hdr, _ := br.Peek(len(sqliteMagic) + 2)
+	if len(hdr) < len(sqliteMagic)+2 || !bytes.Equal(hdr[:len(sqliteMagic)], sqliteMagic) {
+		return 0
+	}
+
+	size := int64(binary.BigEndian.Uint16(hdr[len(sqliteMagic):]))
+	if size == 1 {
+		return 65536
+	}
+	if size < 512 || size&(size-1) != 0 {
+		return 0
+	}
+	return size
+}
+
+// PageSize returns the page size used by the splitter.
+func (as *AlignedSplitter) PageSize() int64 {
+	return as.pageSize
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (as *AlignedSplitter) Reader() io.Reader {
+	return as.r
+}
+
+// NextBytes produces a new chunk.
+func (as *AlignedSplitter) NextBytes() ([]byte, error) {
+	return as.trap.result(as.nextBytes())
+}
+
+func (as *AlignedSplitter) nextBytes() ([]byte, error) {
+	if as.header != nil {
+		b, err := as.header.NextBytes()
+		if err != io.EOF {
+			return b, err
+		}
+		as.header = nil
+	}
+	return as.pages.NextBytes()
+}
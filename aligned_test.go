@@ -0,0 +1,86 @@
+package chunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestAlignedSplitterSQLite(t *testing.T) {
+	data := randBuf(t, 4096*100+10)
+	copy(data, sqliteMagic)
+	binary.BigEndian.PutUint16(data[len(sqliteMagic):], 4096)
+
+	s, err := NewAlignedSplitter(bytes.NewReader(data), 0, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.PageSize() != 4096 {
+		t.Fatalf("expected a page size of 4096, got %d", s.PageSize())
+	}
+
+	chunks := splitAll(t, s)
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+	for i, chunk := range chunks[:len(chunks)-1] {
+		if len(chunk) != 3*4096 {
+			t.Fatalf("chunk %d/%d has size %d", i+1, len(chunks), len(chunk))
+		}
+	}
+
+	binary.BigEndian.PutUint16(data[len(sqliteMagic):], 1)
+	s, err = NewAlignedSplitter(bytes.NewReader(data), 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.PageSize() != 65536 {
+		t.Fatalf("expected a page size of 65536, got %d", s.PageSize())
+	}
+	if chunk, _ := s.NextBytes(); len(chunk) != int(DefaultBlockSize) {
+		t.Fatalf("expected a chunk of %d bytes, got %d", DefaultBlockSize, len(chunk))
+	}
+}
+
+func TestAlignedSplitterHeader(t *testing.T) {
+	data := randBuf(t, 100+512*40)
+
+	s, err := NewAlignedSplitter(bytes.NewReader(data), 512, 8, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := splitAll(t, s)
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+	want := []int{100, 4096, 4096, 4096, 4096, 4096}
+	got := chunkSizes(chunks)
+	if len(got) != len(want) {
+		t.Fatalf("expected chunk sizes %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected chunk sizes %v, got %v", want, got)
+		}
+	}
+}
+
+func TestAlignedSplitterErrors(t *testing.T) {
+	r := bytes.NewReader(randBuf(t, 1000))
+
+	if _, err := NewAlignedSplitter(r, 0, 1, 0); err != ErrPageSize {
+		t.Fatalf("Expected 'ErrPageSize', got: %#v", err)
+	}
+	if _, err := FromString(r, "sqlite"); err != ErrPageSize {
+		t.Fatalf("Expected 'ErrPageSize', got: %#v", err)
+	}
+	if _, err := NewAlignedSplitter(r, 4096, -1, 0); err != ErrSize {
+		t.Fatalf("Expected 'ErrSize', got: %#v", err)
+	}
+	if _, err := NewAlignedSplitter(r, 4096, 1024, 0); err != ErrSizeMax {
+		t.Fatalf("Expected 'ErrSizeMax', got: %#v", err)
+	}
+	if _, err := NewAlignedSplitter(r, 1<<32, 1<<32, 0); err != ErrSizeMax {
+		t.Fatalf("Expected 'ErrSizeMax', got: %#v", err)
+	}
+}
@@ -3,21 +3,24 @@ package chunk
 import (
 	"io"
 	"math/bits"
-
-	pool "github.com/libp2p/go-buffer-pool"
 )
 
 const (
 	buzMin  = 128 << 10
 	buzMax  = 512 << 10
 	buzMask = 1<<17 - 1
+	// buzBackupMask is the secondary divisor used by NewBuzhashTTTD.
+	buzBackupMask = buzMask >> 1
 )
 
 // Deprecated: use github.com/ipfs/boxo/chunker.Buzhash
 type Buzhash struct {
-	r   io.Reader
-	buf []byte
-	n   int
+	r    io.Reader
+	trap *readTrap
+	buf  []byte
+	n    int
+
+	tttd bool
 
 	err error
 }
@@ -25,21 +28,40 @@ type Buzhash struct {
 // Deprecated: use github.com/ipfs/boxo/chunker.NewBuzhash
 func NewBuzhash(r io.Reader) *Buzhash {
 	return &Buzhash{
-		r:   r,
-		buf: pool.Get(buzMax),
+		r:    r,
+		trap: newReadTrap(r),
+		buf:  getBuf(buzMax),
 	}
 }
 
+// NewBuzhashTTTD returns a Buzhash splitter using the Two-Threshold
+// Two-Divisor algorithm, like NewRabinTTTD.
+func NewBuzhashTTTD(r io.Reader) *Buzhash {
+	b := NewBuzhash(r)
+	b.tttd = true
+	return b
+}
+
+// MaxChunkSize returns the size at which chunks are cut when no boundary
+// is found.
+func (b *Buzhash) MaxChunkSize() int {
+	return buzMax
+}
+
 func (b *Buzhash) Reader() io.Reader {
 	return b.r
 }
 
 func (b *Buzhash) NextBytes() ([]byte, error) {
+	return b.trap.result(b.nextBytes())
+}
+
+func (b *Buzhash) nextBytes() ([]byte, error) {
 	if b.err != nil {
 		return nil, b.err
 	}
 
-	n, err := io.ReadFull(b.r, b.buf[b.n:])
+	n, err := io.ReadFull(b.trap, b.buf[b.n:])
 	if err != nil {
 		if err == io.ErrUnexpectedEOF || err == io.EOF {
 			buffered := b.n + n
@@ -47,20 +69,20 @@ func (b *Buzhash) NextBytes() ([]byte, error) {
 				b.err = io.EOF
 				// Read nothing? Don't return an empty block.
 				if buffered == 0 {
-					pool.Put(b.buf)
+					putBuf(b.buf)
 					b.buf = nil
 					return nil, b.err
 				}
 				res := make([]byte, buffered)
 				copy(res, b.buf)
 
-				pool.Put(b.buf)
+				putBuf(b.buf)
 				b.buf = nil
 				return res, nil
 			}
 		} else {
 			b.err = err
-			pool.Put(b.buf)
+			putBuf(b.buf)
 			b.buf = nil
 			return nil, err
 		}
@@ -88,13 +110,33 @@ func (b *Buzhash) NextBytes() ([]byte, error) {
 		_ = buf[max]
 		_ = bufshf[max]
 
-		for ; i <= max; i++ {
-			if state&buzMask == 0 {
-				break
+		if b.tttd {
+			backup := -1
+			for ; i <= max; i++ {
+				if state&buzMask == 0 {
+					break
+				}
+				if state&buzBackupMask == 0 {
+					backup = i
+				}
+				state = bits.RotateLeft32(state, 1) ^
+					bytehash[buf[i]] ^
+					bytehash[bufshf[i]]
+			}
+			// Only fall back when the chunk would be cut at buzMax, not
+			// when the input simply ended.
+			if i > max && backup >= 0 && err == nil {
+				i = backup
+			}
+		} else {
+			for ; i <= max; i++ {
+				if state&buzMask == 0 {
+					break
+				}
+				state = bits.RotateLeft32(state, 1) ^
+					bytehash[buf[i]] ^
+					bytehash[bufshf[i]]
 			}
-			state = bits.RotateLeft32(state, 1) ^
-				bytehash[buf[i]] ^
-				bytehash[bufshf[i]]
 		}
 		i += 32
 	}
@@ -0,0 +1,99 @@
+package chunk
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"io"
+)
+
+// casync index format constants, see casync's caformat.h.
+const (
+	caFormatIndex           = 0x96824d9c7b129ff9
+	caFormatTable           = 0xe75b9e112f17417d
+	caFormatTableTailMarker = 0x4b4f050e5549ecd1
+	caFormatSHA512256       = 0x2000000000000000
+
+	caIndexHeaderSize = 48
+	caTableHeaderSize = 16
+	caTableItemSize   = 40
+	caTableTailSize   = 40
+)
+
+// CaibxWriter writes a list of chunks as a casync blob index (.caibx).
+// Chunk IDs are the SHA512/256 digests of the uncompressed chunk data, as
+// used by casync and desync by default.
+type CaibxWriter struct {
+	w      io.Writer
+	offset uint64
+	count  uint64
+}
+
+// NewCaibxWriter writes the index and table headers to w and returns a
+// CaibxWriter to which chunks can be added in order.
+func NewCaibxWriter(w io.Writer, min, avg, max uint64) (*CaibxWriter, error) {
+	var hdr [caIndexHeaderSize + caTableHeaderSize]byte
+	binary.LittleEndian.PutUint64(hdr[0:], caIndexHeaderSize)
+	binary.LittleEndian.PutUint64(hdr[8:], caFormatIndex)
+	binary.LittleEndian.PutUint64(hdr[16:], caFormatSHA512256)
+	binary.LittleEndian.PutUint64(hdr[24:], min)
+	binary.LittleEndian.PutUint64(hdr[32:], avg)
+	binary.LittleEndian.PutUint64(hdr[40:], max)
+	// The table size is unknown up front and recorded in the tail instead.
+	binary.LittleEndian.PutUint64(hdr[48:], ^uint64(0))
+	binary.LittleEndian.PutUint64(hdr[56:], caFormatTable)
+
+	if _, err := w.Write(hdr[:]); err != nil {
+		return nil, err
+	}
+	return &CaibxWriter{w: w}, nil
+}
+
+// Add appends a table entry for chunk.
+func (cw *CaibxWriter) Add(chunk []byte) error {
+	cw.offset += uint64(len(chunk))
+	cw.count++
+
+	var item [caTableItemSize]byte
+	binary.LittleEndian.PutUint64(item[0:], cw.offset)
+	id := sha512.Sum512_256(chunk)
+	copy(item[8:], id[:])
+
+	_, err := cw.w.Write(item[:])
+	return err
+}
+
+// Close writes the table tail. It does not close the underlying writer.
+func (cw *CaibxWriter) Close() error {
+	var tail [caTableTailSize]byte
+	binary.LittleEndian.PutUint64(tail[16:], caIndexHeaderSize)
+	binary.LittleEndian.PutUint64(tail[24:], caTableHeaderSize+cw.count*caTableItemSize+caTableTailSize)
+	binary.LittleEndian.PutUint64(tail[32:], caFormatTableTailMarker)
+
+	_, err := cw.w.Write(tail[:])
+	return err
+}
+
+// WriteCaibx consumes all chunks from c and writes them to w as a casync
+// blob index.
+func WriteCaibx(w io.Writer, c *Casync) error {
+	min, avg, max := c.ChunkSizes()
+	cw, err := NewCaibxWriter(w, min, avg, max)
+	if err != nil {
+		return err
+	}
+
+	for {
+		b, err := c.NextBytes()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if err := cw.Add(b); err != nil {
+			return err
+		}
+	}
+
+	return cw.Close()
+}
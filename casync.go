@@ -0,0 +1,172 @@
+package chunk
+
+import (
+	"io"
+	"math/bits"
+)
+
+const (
+	// casyncWindow is the size of the rolling hash window used by casync.
+	casyncWindow = 48
+
+	casyncAvg = 64 << 10
+	casyncMin = casyncAvg / 4
+	casyncMax = casyncAvg * 4
+)
+
+// Casync implements the Splitter interface and cuts chunks the way casync
+// and desync do: a buzhash over a 48 byte window that restarts at every
+// chunk, and a cut wherever hash % discriminator == discriminator - 1 once
+// the chunk has reached its minimum size.
+//
+// NewCasync and NewCasyncMinMax use casync's own buzhash table, so they
+// reproduce the boundaries of an existing casync or desync store.
+type Casync struct {
+	r    io.Reader
+	trap *readTrap
+	buf  []byte
+	n    int
+
+	table         *[256]uint32
+	min, avg, max int
+	discriminator uint32
+
+	err error
+}
+
+// NewCasync returns a Casync splitter using casync's default chunk sizes
+// (16KiB min, 64KiB avg, 256KiB max).
+func NewCasync(r io.Reader) *Casync {
+	return newCasync(r, &casyncTable, casyncMin, casyncAvg, casyncMax)
+}
+
+// NewCasyncMinMax returns a Casync splitter which uses the given min,
+// average and max chunk sizes. min must be at least 48 bytes, avg must lie
+// strictly between min and max, and max may not exceed ChunkSizeLimit.
+func NewCasyncMinMax(r io.Reader, min, avg, max uint64) (*Casync, error) {
+	return NewCasyncWithTable(r, &casyncTable, min, avg, max)
+}
+
+// NewCasyncWithTable returns a Casync splitter which uses the given
+// buzhash byte table in place of casync's.
+func NewCasyncWithTable(r io.Reader, table *[256]uint32, min, avg, max uint64) (*Casync, error) {
+	switch {
+	case min < casyncWindow:
+		return nil, ErrCasyncMin
+	case avg <= min || avg >= max:
+		return nil, ErrCasyncAvg
+	case max > uint64(ChunkSizeLimit):
+		return nil, ErrSizeMax
+	}
+	return newCasync(r, table, min, avg, max), nil
+}
+
+func newCasync(r io.Reader, table *[256]uint32, min, avg, max uint64) *Casync {
+	return &Casync{
+		r:             r,
+		trap:          newReadTrap(r),
+		buf:           getBuf(int(max)),
+		table:         table,
+		min:           int(min),
+		avg:           int(avg),
+		max:           int(max),
+		discriminator: casyncDiscriminator(avg),
+	}
+}
+
+// casyncDiscriminator mirrors discriminator_from_avg() in casync.
+func casyncDiscriminator(avg uint64) uint32 {
+	return uint32(float64(avg) / (-1.42888852e-7*float64(avg) + 1.33237515))
+}
+
+// ChunkSizes returns the min, average and max chunk sizes of the splitter.
+func (c *Casync) ChunkSizes() (min, avg, max uint64) {
+	return uint64(c.min), uint64(c.avg), uint64(c.max)
+}
+
+// MaxChunkSize returns the size at which chunks are cut when no boundary
+// is found.
+func (c *Casync) MaxChunkSize() int {
+	return c.max
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (c *Casync) Reader() io.Reader {
+	return c.r
+}
+
+// NextBytes produces a new chunk.
+func (c *Casync) NextBytes() ([]byte, error) {
+	return c.trap.result(c.nextBytes())
+}
+
+func (c *Casync) nextBytes() ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	n, err := io.ReadFull(c.trap, c.buf[c.n:])
+	buffered := c.n + n
+	if err != nil {
+		if err != io.ErrUnexpectedEOF && err != io.EOF {
+			c.err = err
+			putBuf(c.buf)
+			c.buf = nil
+			return nil, err
+		}
+		if buffered <= c.min {
+			c.err = io.EOF
+			// Read nothing? Don't return an empty block.
+			if buffered == 0 {
+				putBuf(c.buf)
+				c.buf = nil
+				return nil, c.err
+			}
+			res := make([]byte, buffered)
+			copy(res, c.buf)
+
+			putBuf(c.buf)
+			c.buf = nil
+			return res, nil
+		}
+	}
+
+	i := c.boundary(c.buf[:buffered])
+
+	res := make([]byte, i)
+	copy(res, c.buf)
+
+	c.n = copy(c.buf, c.buf[i:buffered])
+
+	return res, nil
+}
+
+// boundary returns the length of the chunk at the start of buf. If no
+// boundary is found before the end of a short buffer, len(buf) is returned.
+func (c *Casync) boundary(buf []byte) int {
+	i := c.min
+	if i < casyncWindow {
+		i = casyncWindow
+	}
+	if i >= len(buf) {
+		return len(buf)
+	}
+
+	var state uint32
+	for j, b := range buf[i-casyncWindow : i] {
+		state ^= bits.RotateLeft32(c.table[b], casyncWindow-j-1)
+	}
+
+	// Like casync, a chunk is never cut at exactly its minimum size: the
+	// first candidate boundary comes after one more byte is hashed.
+	for i < len(buf) {
+		state = bits.RotateLeft32(state, 1) ^
+			bits.RotateLeft32(c.table[buf[i-casyncWindow]], casyncWindow) ^
+			c.table[buf[i]]
+		i++
+		if i >= c.max || state%c.discriminator == c.discriminator-1 {
+			return i
+		}
+	}
+	return i
+}
@@ -0,0 +1,161 @@
+package chunk
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/ipfs/go-ipfs-chunker/conformance"
+)
+
+// casyncVectors hold boundaries produced by desync v0.9.5's NewChunker on
+// SplitMix64 output (see conformance.NewSplitMix64).
+var casyncVectors = []struct {
+	seed          uint64
+	size          int
+	min, avg, max uint64
+	bounds        []int
+}{
+	{
+		seed: 26, size: 16 << 10, min: 64, avg: 256, max: 1024,
+		bounds: []int{
+			332, 737, 1128, 1255, 1364, 1569, 2023, 2131, 2555, 2622,
+			2764, 3451, 3523, 3714, 4162, 4422, 4517, 4801, 4867, 5073,
+			5204, 5707, 5824, 6179, 6461, 6705, 6849, 6944, 7301, 7825,
+			7970, 8235, 8318, 8597, 8905, 9057, 9561, 9805, 9975, 10111,
+			10389, 10672, 10755, 10826, 11473, 11810, 12188, 12732, 12847, 12967,
+			13163, 13387, 13766, 14250, 14378, 14444, 14643, 14805, 14945, 15013,
+			15458, 15658, 15842, 15914, 16168, 16384,
+		},
+	},
+	{
+		seed: 26, size: 1 << 20, min: casyncMin, avg: casyncAvg, max: casyncMax,
+		bounds: []int{
+			33645, 68717, 92765, 133883, 155721, 255906, 308987, 350415, 383205, 548859,
+			570071, 615590, 745085, 961765, 1018051, 1042022, 1048576,
+		},
+	},
+}
+
+func TestCasyncMatchesDesync(t *testing.T) {
+	for _, v := range casyncVectors {
+		data := make([]byte, v.size)
+		conformance.NewSplitMix64(v.seed).Read(data)
+
+		c, err := NewCasyncMinMax(bytes.NewReader(data), v.min, v.avg, v.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int
+		var off int
+		for {
+			chunk, err := c.NextBytes()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatal(err)
+			}
+			if !bytes.Equal(chunk, data[off:off+len(chunk)]) {
+				t.Fatalf("chunk at %d does not match input", off)
+			}
+			off += len(chunk)
+			got = append(got, off)
+		}
+
+		if len(got) != len(v.bounds) {
+			t.Fatalf("avg %d: expected %d chunks, got %d", v.avg, len(v.bounds), len(got))
+		}
+		for i := range v.bounds {
+			if got[i] != v.bounds[i] {
+				t.Fatalf("avg %d: boundary %d: expected %d, got %d", v.avg, i, v.bounds[i], got[i])
+			}
+		}
+	}
+}
+
+func TestCasyncInvalid(t *testing.T) {
+	for _, c := range []struct {
+		min, avg, max uint64
+		err           error
+	}{
+		{0, 0, 0, ErrCasyncMin},
+		{48, 64, 0, ErrCasyncAvg},
+		{1024, 512, 256, ErrCasyncAvg},
+		{48, 48, 128, ErrCasyncAvg},
+		{48, 64, uint64(ChunkSizeLimit) + 1, ErrSizeMax},
+	} {
+		if _, err := NewCasyncMinMax(bytes.NewReader(nil), c.min, c.avg, c.max); err != c.err {
+			t.Fatalf("%d-%d-%d: expected %v, got %v", c.min, c.avg, c.max, c.err, err)
+		}
+	}
+}
+
+func TestCasyncChunkReuse(t *testing.T) {
+	newCasync := func(r io.Reader) Splitter {
+		return NewCasync(r)
+	}
+	testReuse(t, newCasync)
+}
+
+func TestCaibx(t *testing.T) {
+	data := randBuf(t, 1<<20)
+
+	var idx bytes.Buffer
+	if err := WriteCaibx(&idx, NewCasync(bytes.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+
+	var chunks [][]byte
+	c := NewCasync(bytes.NewReader(data))
+	for {
+		chunk, err := c.NextBytes()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	b := idx.Bytes()
+	le := binary.LittleEndian
+	if len(b) != caIndexHeaderSize+caTableHeaderSize+len(chunks)*caTableItemSize+caTableTailSize {
+		t.Fatalf("unexpected index size %d for %d chunks", len(b), len(chunks))
+	}
+	if le.Uint64(b[8:]) != caFormatIndex || le.Uint64(b[56:]) != caFormatTable {
+		t.Fatal("bad index header")
+	}
+	if le.Uint64(b[24:]) != casyncMin || le.Uint64(b[32:]) != casyncAvg || le.Uint64(b[40:]) != casyncMax {
+		t.Fatal("bad chunk sizes in index header")
+	}
+
+	items := b[caIndexHeaderSize+caTableHeaderSize:]
+	var off uint64
+	for i, chunk := range chunks {
+		item := items[i*caTableItemSize:]
+		off += uint64(len(chunk))
+		if le.Uint64(item) != off {
+			t.Fatalf("entry %d: expected offset %d, got %d", i, off, le.Uint64(item))
+		}
+		id := sha512.Sum512_256(chunk)
+		if !bytes.Equal(item[8:40], id[:]) {
+			t.Fatalf("entry %d: bad chunk id", i)
+		}
+	}
+
+	tail := b[len(b)-caTableTailSize:]
+	if le.Uint64(tail[16:]) != caIndexHeaderSize ||
+		le.Uint64(tail[24:]) != uint64(len(b)-caIndexHeaderSize) ||
+		le.Uint64(tail[32:]) != caFormatTableTailMarker {
+		t.Fatal("bad table tail")
+	}
+}
+
+func BenchmarkCasync(b *testing.B) {
+	benchmarkChunker(b, func(r io.Reader) Splitter {
+		return NewCasync(r)
+	})
+}
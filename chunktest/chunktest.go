@@ -0,0 +1,200 @@
+// Package chunktest provides a test harness checking that a chunk.Splitter
+// implementation behaves the way the importers expect.
+package chunktest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+
+	chunk "github.com/ipfs/go-ipfs-chunker"
+)
+
+// Options configures TestSplitter.
+type Options struct {
+	// MaxSize is the maximum size of the chunks produced by the splitter.
+	// chunk.ChunkSizeLimit is used when 0.
+	MaxSize int
+	// Sizes lists the sizes of the random inputs to chunk. When empty,
+	// inputs from 0 bytes to 4MiB are used.
+	Sizes []int
+	// Seed seeds the generator of the random inputs.
+	Seed int64
+	// Input, if set, is used to generate the inputs in place of random
+	// data, for splitters expecting a specific format.
+	Input func(size int) []byte
+}
+
+var defaultSizes = []int{0, 1, 1000, 256<<10 + 7, 4 << 20}
+
+// errRead is returned by the failing readers.
+var errRead = errors.New("chunktest: read error")
+
+// TestSplitter runs the checks against the splitters returned by gen:
+//
+//   - Reassembly: the chunks concatenate to the input.
+//   - NoEmptyChunks: no chunk is empty.
+//   - MaxSize: no chunk is larger than opts.MaxSize.
+//   - EOF: once io.EOF is returned, every following call returns io.EOF.
+//   - NoAliasing: chunks are not modified by later calls to NextBytes.
+//   - ShortReads: the boundaries do not depend on how the reader splits
+//     its reads.
+//   - ReadError: every byte read before the reader failed is delivered,
+//     then NextBytes returns the reader's error, wrapped or not. When it is
+//     a *chunk.ReadError, its offset is checked too.
+func TestSplitter(t *testing.T, gen chunk.SplitterGen, opts *Options) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	max := opts.MaxSize
+	if max == 0 {
+		max = chunk.ChunkSizeLimit
+	}
+	sizes := opts.Sizes
+	if len(sizes) == 0 {
+		sizes = defaultSizes
+	}
+
+	rnd := rand.New(rand.NewSource(opts.Seed))
+	for _, size := range sizes {
+		var data []byte
+		if opts.Input != nil {
+			data = opts.Input(size)
+		} else {
+			data = make([]byte, size)
+			rnd.Read(data)
+		}
+
+		t.Run(fmt.Sprintf("%d", size), func(t *testing.T) {
+			t.Run("Reassembly", func(t *testing.T) { testReassembly(t, gen, data) })
+			t.Run("NoEmptyChunks", func(t *testing.T) { testNoEmptyChunks(t, gen, data) })
+			t.Run("MaxSize", func(t *testing.T) { testMaxSize(t, gen, data, max) })
+			t.Run("EOF", func(t *testing.T) { testEOF(t, gen, data) })
+			t.Run("NoAliasing", func(t *testing.T) { testNoAliasing(t, gen, data) })
+			t.Run("ShortReads", func(t *testing.T) { testShortReads(t, gen, data) })
+			t.Run("ReadError", func(t *testing.T) { testReadError(t, gen, data) })
+		})
+	}
+}
+
+// split returns all the chunks of data, copied as they are returned.
+func split(t testing.TB, s chunk.Splitter) [][]byte {
+	t.Helper()
+	var chunks [][]byte
+	for {
+		b, err := s.NextBytes()
+		if err == io.EOF {
+			return chunks
+		} else if err != nil {
+			t.Fatalf("NextBytes: %s", err)
+		}
+		chunks = append(chunks, append([]byte(nil), b...))
+	}
+}
+
+func testReassembly(t testing.TB, gen chunk.SplitterGen, data []byte) {
+	chunks := split(t, gen(bytes.NewReader(data)))
+	if got := bytes.Join(chunks, nil); !bytes.Equal(got, data) {
+		t.Fatalf("chunks do not reassemble to the input: got %d bytes, expected %d", len(got), len(data))
+	}
+}
+
+func testNoEmptyChunks(t testing.TB, gen chunk.SplitterGen, data []byte) {
+	for i, c := range split(t, gen(bytes.NewReader(data))) {
+		if len(c) == 0 {
+			t.Fatalf("chunk %d is empty", i)
+		}
+	}
+}
+
+func testMaxSize(t testing.TB, gen chunk.SplitterGen, data []byte, max int) {
+	for i, c := range split(t, gen(bytes.NewReader(data))) {
+		if len(c) > max {
+			t.Fatalf("chunk %d has %d bytes, more than %d", i, len(c), max)
+		}
+	}
+}
+
+func testEOF(t testing.TB, gen chunk.SplitterGen, data []byte) {
+	s := gen(bytes.NewReader(data))
+	split(t, s)
+	for i := 0; i < 3; i++ {
+		b, err := s.NextBytes()
+		if err != io.EOF {
+			t.Fatalf("call %d after EOF returned %v, expected io.EOF", i, err)
+		}
+		if len(b) != 0 {
+			t.Fatalf("call %d after EOF returned %d bytes", i, len(b))
+		}
+	}
+}
+
+func testNoAliasing(t testing.TB, gen chunk.SplitterGen, data []byte) {
+	s := gen(bytes.NewReader(data))
+	var chunks, copies [][]byte
+	for {
+		b, err := s.NextBytes()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("NextBytes: %s", err)
+		}
+		chunks = append(chunks, b)
+		copies = append(copies, append([]byte(nil), b...))
+	}
+	for i := range chunks {
+		if !bytes.Equal(chunks[i], copies[i]) {
+			t.Fatalf("chunk %d was modified by a later call to NextBytes", i)
+		}
+	}
+}
+
+func testShortReads(t testing.TB, gen chunk.SplitterGen, data []byte) {
+	expected := split(t, gen(bytes.NewReader(data)))
+	for name, r := range map[string]io.Reader{
+		"OneByte": iotest.OneByteReader(bytes.NewReader(data)),
+		"Half":    iotest.HalfReader(bytes.NewReader(data)),
+		"DataErr": iotest.DataErrReader(bytes.NewReader(data)),
+	} {
+		got := split(t, gen(r))
+		if len(got) != len(expected) {
+			t.Fatalf("%s reader: got %d chunks, expected %d", name, len(got), len(expected))
+		}
+		for i := range got {
+			if !bytes.Equal(got[i], expected[i]) {
+				t.Fatalf("%s reader: chunk %d differs", name, i)
+			}
+		}
+	}
+}
+
+func testReadError(t testing.TB, gen chunk.SplitterGen, data []byte) {
+	for _, at := range []int{0, len(data) / 2, len(data)} {
+		r := io.MultiReader(bytes.NewReader(data[:at]), iotest.ErrReader(errRead))
+		s := gen(r)
+
+		var got []byte
+		for {
+			b, err := s.NextBytes()
+			if err == nil {
+				got = append(got, b...)
+				continue
+			}
+			if !errors.Is(err, errRead) {
+				t.Fatalf("error after %d bytes: got %v, expected the reader's error", at, err)
+			}
+			var rerr *chunk.ReadError
+			if errors.As(err, &rerr) && rerr.Offset != int64(at) {
+				t.Fatalf("error after %d bytes: reported at offset %d", at, rerr.Offset)
+			}
+			break
+		}
+		if !bytes.Equal(got, data[:at]) {
+			t.Fatalf("error after %d bytes: %d bytes delivered do not match the input", at, len(got))
+		}
+	}
+}
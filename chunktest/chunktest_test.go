@@ -0,0 +1,149 @@
+package chunktest
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	chunk "github.com/ipfs/go-ipfs-chunker"
+)
+
+func TestSplitters(t *testing.T) {
+	for _, c := range []struct {
+		spec string
+		max  int
+	}{
+		{"default", int(chunk.DefaultBlockSize)},
+		{"size-1000", 1000},
+		{"rabin", 393216},
+		{"rabin-tttd", 393216},
+		{"buzhash", 512 << 10},
+		{"buzhash-tttd", 512 << 10},
+		{"casync", 256 << 10},
+		{"rollsum", 32 << 10},
+		{"lines-4096", 0},
+		{"csv-4096", 0},
+		{"varint-65536", 0},
+		{"sparse-65536", int(chunk.DefaultBlockSize)},
+		{"tar", 0},
+		{"gzip", 0},
+		{"mp4", 0},
+	} {
+		spec := c.spec
+		t.Run(spec, func(t *testing.T) {
+			TestSplitter(t, func(r io.Reader) chunk.Splitter {
+				s, err := chunk.FromString(r, spec)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return s
+			}, &Options{MaxSize: c.max, Seed: 1})
+		})
+	}
+}
+
+// fakeTB records the failures of a check instead of failing the test.
+type fakeTB struct {
+	testing.TB
+	failed bool
+	msg    string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.failed = true
+	f.msg = fmt.Sprintf(format, args...)
+}
+
+func (f *fakeTB) Fatalf(format string, args ...interface{}) {
+	f.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// runCheck runs check against a fakeTB, in a goroutine of its own so that
+// Fatalf can stop it.
+func runCheck(check func(t testing.TB)) *fakeTB {
+	f := &fakeTB{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		check(f)
+	}()
+	<-done
+	return f
+}
+
+// reusingSplitter returns every chunk in the same buffer.
+type reusingSplitter struct {
+	r   io.Reader
+	buf [1000]byte
+}
+
+func (s *reusingSplitter) NextBytes() ([]byte, error) {
+	n, err := io.ReadFull(s.r, s.buf[:])
+	if n > 0 {
+		return s.buf[:n], nil
+	} else if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return nil, err
+}
+
+func (s *reusingSplitter) Reader() io.Reader {
+	return s.r
+}
+
+// emptySplitter returns an empty chunk before the chunks of s.
+type emptySplitter struct {
+	chunk.Splitter
+	started bool
+}
+
+func (s *emptySplitter) NextBytes() ([]byte, error) {
+	if !s.started {
+		s.started = true
+		return []byte{}, nil
+	}
+	return s.Splitter.NextBytes()
+}
+
+// swallowingSplitter ends the chunks of s with io.EOF on read errors.
+type swallowingSplitter struct {
+	chunk.Splitter
+}
+
+func (s swallowingSplitter) NextBytes() ([]byte, error) {
+	b, err := s.Splitter.NextBytes()
+	if err != nil {
+		err = io.EOF
+	}
+	return b, err
+}
+
+func TestBrokenSplitters(t *testing.T) {
+	data := make([]byte, 10000)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	sized := chunk.SizeSplitterGen(1000)
+	for _, c := range []struct {
+		name  string
+		gen   chunk.SplitterGen
+		check func(t testing.TB, gen chunk.SplitterGen, data []byte)
+	}{
+		{"reuse", func(r io.Reader) chunk.Splitter { return &reusingSplitter{r: r} }, testNoAliasing},
+		{"empty", func(r io.Reader) chunk.Splitter { return &emptySplitter{Splitter: sized(r)} }, testNoEmptyChunks},
+		{"swallow", func(r io.Reader) chunk.Splitter { return swallowingSplitter{sized(r)} }, testReadError},
+		{"oversized", chunk.SizeSplitterGen(1001), func(t testing.TB, gen chunk.SplitterGen, data []byte) { testMaxSize(t, gen, data, 1000) }},
+	} {
+		// The checks pass on a correct splitter.
+		if f := runCheck(func(tb testing.TB) { c.check(tb, sized, data) }); f.failed {
+			t.Fatalf("%s: the check failed on a correct splitter: %s", c.name, f.msg)
+		}
+		if f := runCheck(func(tb testing.TB) { c.check(tb, c.gen, data) }); !f.failed {
+			t.Fatalf("%s: the broken splitter was not reported", c.name)
+		}
+	}
+}
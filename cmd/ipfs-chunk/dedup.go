@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	chunk "github.com/ipfs/go-ipfs-chunker"
+)
+
+var defaultDedupSpecs = []string{"size-262144", "rabin", "buzhash"}
+
+// specList is a flag.Value collecting repeated -chunker flags.
+type specList []string
+
+func (l *specList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *specList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+type dedupResult struct {
+	Spec         string  `json:"spec"`
+	TotalBytes   int64   `json:"totalBytes"`
+	UniqueBytes  int64   `json:"uniqueBytes"`
+	TotalChunks  int     `json:"totalChunks"`
+	UniqueChunks int     `json:"uniqueChunks"`
+	Ratio        float64 `json:"ratio"`
+	Throughput   float64 `json:"bytesPerSecond"`
+}
+
+func newDedupResult(spec string, st chunk.DedupStats) dedupResult {
+	return dedupResult{
+		Spec:         spec,
+		TotalBytes:   st.TotalBytes,
+		UniqueBytes:  st.UniqueBytes,
+		TotalChunks:  st.TotalChunks,
+		UniqueChunks: st.UniqueChunks,
+		Ratio:        st.Ratio(),
+		Throughput:   st.Throughput(),
+	}
+}
+
+func dedupCmd(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("dedup", flag.ContinueOnError)
+	var specs specList
+	fs.Var(&specs, "chunker", "chunker spec to compare, may be repeated (default "+strings.Join(defaultDedupSpecs, ", ")+")")
+	asJSON := fs.Bool("json", false, "print the results as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: ipfs-chunk dedup [flags] path...\n\n")
+		fmt.Fprintf(fs.Output(), "Chunks every file under the given paths with each chunker, in parallel,\nand reports how many bytes and blocks would actually be stored. Pass\nseveral versions of a file to measure how well they deduplicate.\n\n")
+		fs.PrintDefaults()
+	}
+	paths, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	if len(specs) == 0 {
+		specs = defaultDedupSpecs
+	}
+
+	files, err := listFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	results, err := dedup(files, specs)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+	return printDedup(w, results)
+}
+
+// listFiles returns the regular files in paths, walking directories in
+// lexical order.
+func listFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		err := filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.Mode().IsRegular() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// dedup chunks files with every spec, one goroutine per spec.
+func dedup(files, specs []string) ([]dedupResult, error) {
+	results := make([]dedupResult, len(specs))
+	errs := make([]error, len(specs))
+
+	var wg sync.WaitGroup
+	for i, spec := range specs {
+		wg.Add(1)
+		go func(i int, spec string) {
+			defer wg.Done()
+
+			dc := chunk.NewDedupCounter(spec)
+			for _, f := range files {
+				if err := addFile(dc, f); err != nil {
+					errs[i] = fmt.Errorf("%s: %s: %w", spec, f, err)
+					return
+				}
+			}
+
+			results[i] = newDedupResult(spec, dc.Stats())
+		}(i, spec)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func addFile(dc *chunk.DedupCounter, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return dc.Add(f)
+}
+
+func printDedup(w io.Writer, results []dedupResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "SPEC\tTOTAL\tUNIQUE\tBLOCKS\tUNIQUE BLOCKS\tRATIO\tMB/s\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%.3f\t%.1f\t\n",
+			r.Spec, r.TotalBytes, r.UniqueBytes, r.TotalChunks, r.UniqueChunks,
+			r.Ratio, r.Throughput/(1<<20))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDedupCmd(t *testing.T) {
+	data := randBuf(t, 3, 100000)
+	paths := writeFiles(t, data, data)
+
+	var out bytes.Buffer
+	args := []string{filepath.Dir(paths[0]), "-chunker", "size-4096", "-json", "-chunker", "rabin-4096"}
+	if err := dedupCmd(&out, args); err != nil {
+		t.Fatal(err)
+	}
+
+	var results []dedupResult
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Spec != "size-4096" || results[1].Spec != "rabin-4096" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	for _, r := range results {
+		if r.TotalBytes != 2*int64(len(data)) || r.UniqueBytes != int64(len(data)) {
+			t.Fatalf("%s: expected %d unique of %d bytes, got %d of %d",
+				r.Spec, len(data), 2*len(data), r.UniqueBytes, r.TotalBytes)
+		}
+		if r.TotalChunks != 2*r.UniqueChunks || r.Ratio != 2 {
+			t.Fatalf("%s: unexpected chunk counts %+v", r.Spec, r)
+		}
+	}
+	if r := results[0]; r.TotalChunks != 2*25 {
+		t.Fatalf("expected 50 chunks, got %d", r.TotalChunks)
+	}
+}
+
+func TestDedupCmdTable(t *testing.T) {
+	paths := writeFiles(t, randBuf(t, 4, 1000))
+
+	var out bytes.Buffer
+	if err := dedupCmd(&out, []string{paths[0]}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1+len(defaultDedupSpecs) || !strings.Contains(lines[0], "UNIQUE BLOCKS") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	for i, spec := range defaultDedupSpecs {
+		if f := strings.Fields(lines[i+1]); f[0] != spec || f[1] != "1000" {
+			t.Fatalf("unexpected line %q", lines[i+1])
+		}
+	}
+}
+
+func TestDedupCmdUsage(t *testing.T) {
+	if err := dedupCmd(&bytes.Buffer{}, []string{"-json"}); err != errUsage {
+		t.Fatalf("expected errUsage, got %v", err)
+	}
+}
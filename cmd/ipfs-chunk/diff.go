@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	chunk "github.com/ipfs/go-ipfs-chunker"
+)
+
+type diffReport struct {
+	Chunker        string `json:"chunker"`
+	OldSize        int64  `json:"oldSize"`
+	NewSize        int64  `json:"newSize"`
+	SharedChunks   int    `json:"sharedChunks"`
+	SharedBytes    int64  `json:"sharedBytes"`
+	InsertedChunks int    `json:"insertedChunks"`
+	InsertedBytes  int64  `json:"insertedBytes"`
+	RemovedChunks  int    `json:"removedChunks"`
+	RemovedBytes   int64  `json:"removedBytes"`
+	TransferBytes  int64  `json:"transferBytes"`
+}
+
+func diffCmd(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	chunker := fs.String("chunker", "default", "chunker spec, as accepted by chunk.FromString")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	verbose := fs.Bool("v", false, "also list the inserted and removed chunks")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: ipfs-chunk diff [flags] old new\n\n")
+		fmt.Fprintf(fs.Output(), "Chunks two versions of a file and reports which chunks are shared,\ninserted or removed, and how many bytes a peer holding the old version\nwould need to fetch.\n\n")
+		fs.PrintDefaults()
+	}
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 2 {
+		fs.Usage()
+		return errUsage
+	}
+
+	d, err := diffFiles(files[0], files[1], *chunker)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(newDiffReport(*chunker, d))
+	}
+	return printDiff(w, newDiffReport(*chunker, d), d, *verbose)
+}
+
+func diffFiles(oldPath, newPath, spec string) (*chunk.ChunkDiff, error) {
+	old, err := os.Open(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	defer old.Close()
+	new, err := os.Open(newPath)
+	if err != nil {
+		return nil, err
+	}
+	defer new.Close()
+
+	so, err := chunk.FromString(old, spec)
+	if err != nil {
+		return nil, err
+	}
+	sn, err := chunk.FromString(new, spec)
+	if err != nil {
+		return nil, err
+	}
+	return chunk.Diff(so, sn)
+}
+
+func newDiffReport(spec string, d *chunk.ChunkDiff) diffReport {
+	return diffReport{
+		Chunker:        spec,
+		OldSize:        d.OldSize,
+		NewSize:        d.NewSize,
+		SharedChunks:   len(d.Shared),
+		SharedBytes:    d.SharedBytes(),
+		InsertedChunks: len(d.Inserted),
+		InsertedBytes:  d.InsertedBytes(),
+		RemovedChunks:  len(d.Removed),
+		RemovedBytes:   d.RemovedBytes(),
+		TransferBytes:  d.TransferBytes(),
+	}
+}
+
+func printDiff(w io.Writer, r diffReport, d *chunk.ChunkDiff, verbose bool) error {
+	if verbose {
+		for _, c := range d.Removed {
+			fmt.Fprintf(w, "- %d\t%d\t%x\n", c.Offset, c.Length, c.Hash)
+		}
+		for _, c := range d.Inserted {
+			fmt.Fprintf(w, "+ %d\t%d\t%x\n", c.Offset, c.Length, c.Hash)
+		}
+	}
+
+	fmt.Fprintf(w, "shared:   %d chunks, %d bytes\n", r.SharedChunks, r.SharedBytes)
+	fmt.Fprintf(w, "inserted: %d chunks, %d bytes\n", r.InsertedChunks, r.InsertedBytes)
+	fmt.Fprintf(w, "removed:  %d chunks, %d bytes\n", r.RemovedChunks, r.RemovedBytes)
+	reuse := 0.0
+	if r.NewSize > 0 {
+		reuse = 100 * float64(r.SharedBytes) / float64(r.NewSize)
+	}
+	_, err := fmt.Fprintf(w, "transfer: %d of %d bytes (%.1f%% reused)\n", r.TransferBytes, r.NewSize, reuse)
+	return err
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiffCmd(t *testing.T) {
+	old := randBuf(t, 7, 64<<10)
+	new := append(append(append([]byte{}, old[:20000]...), randBuf(t, 8, 100)...), old[20000:]...)
+	paths := writeFiles(t, old, new)
+
+	var out bytes.Buffer
+	if err := diffCmd(&out, []string{paths[0], paths[1], "-chunker", "rabin-4096", "-json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var r diffReport
+	if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Chunker != "rabin-4096" || r.OldSize != int64(len(old)) || r.NewSize != int64(len(new)) {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	if r.SharedBytes+r.InsertedBytes != r.NewSize || r.TransferBytes != r.InsertedBytes {
+		t.Fatalf("inconsistent report %+v", r)
+	}
+	if r.SharedChunks == 0 || r.InsertedChunks == 0 || r.RemovedChunks == 0 || r.InsertedBytes > 16<<10 {
+		t.Fatalf("expected a small change, got %+v", r)
+	}
+}
+
+func TestDiffCmdVerbose(t *testing.T) {
+	old := randBuf(t, 9, 10000)
+	new := append(append([]byte{}, old[:4096]...), randBuf(t, 10, 5904)...)
+	paths := writeFiles(t, old, new)
+
+	var out bytes.Buffer
+	if err := diffCmd(&out, []string{"-v", "-chunker=size-4096", paths[0], paths[1]}); err != nil {
+		t.Fatal(err)
+	}
+	var added, removed int
+	for _, line := range strings.Split(out.String(), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+ "):
+			added++
+		case strings.HasPrefix(line, "- "):
+			removed++
+		}
+	}
+	if added != 2 || removed != 2 {
+		t.Fatalf("expected 2 inserted and 2 removed chunks, got %d and %d:\n%s", added, removed, out.String())
+	}
+	if !strings.Contains(out.String(), "shared:   1 chunks, 4096 bytes\n") {
+		t.Fatalf("unexpected summary:\n%s", out.String())
+	}
+}
+
+func TestDiffCmdUsage(t *testing.T) {
+	paths := writeFiles(t, nil)
+	if err := diffCmd(&bytes.Buffer{}, []string{paths[0]}); err != errUsage {
+		t.Fatalf("expected errUsage, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+// Command ipfs-chunk helps understand and compare the chunkers supported by
+// chunk.FromString.
+//
+// Subcommands:
+//
+//	split   print the chunks of a file (the default)
+//	dedup   compare how several chunkers deduplicate a corpus
+//	tune    search chunk sizes for a sample corpus
+//	diff    compare the chunks of two versions of a file
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// errUsage is returned by commands called with the wrong arguments, after
+// printing their usage.
+var errUsage = errors.New("invalid arguments")
+
+var commands = map[string]func(w io.Writer, args []string) error{
+	"split": splitCmd,
+	"dedup": dedupCmd,
+	"tune":  tuneCmd,
+	"diff":  diffCmd,
+}
+
+func main() {
+	args := os.Args[1:]
+	cmd := splitCmd
+	if len(args) > 0 {
+		if c, ok := commands[args[0]]; ok {
+			cmd, args = c, args[1:]
+		}
+	}
+
+	switch err := cmd(os.Stdout, args); err {
+	case nil, flag.ErrHelp:
+	case errUsage:
+		os.Exit(2)
+	default:
+		fmt.Fprintln(os.Stderr, "ipfs-chunk:", err)
+		os.Exit(1)
+	}
+}
+
+// parseFlags parses args with fs, allowing flags after positional
+// arguments, and returns the positional arguments. Arguments after "--"
+// are never taken as flags. fs must use flag.ContinueOnError; bad flags
+// return errUsage.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err == flag.ErrHelp {
+			return nil, err
+		} else if err != nil {
+			return nil, errUsage
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		if args[0] == "--" {
+			return append(pos, args[1:]...), nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
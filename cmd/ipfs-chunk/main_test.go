@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func randBuf(t *testing.T, seed int64, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(b)
+	return b
+}
+
+// writeFiles writes each content to its own file in a temporary directory
+// and returns their paths.
+func writeFiles(t *testing.T, contents ...[]byte) []string {
+	t.Helper()
+	dir := t.TempDir()
+	var paths []string
+	for i, b := range contents {
+		p := filepath.Join(dir, string(rune('a'+i)))
+		if err := os.WriteFile(p, b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+func TestParseFlags(t *testing.T) {
+	for _, tc := range []struct {
+		args    []string
+		pos     []string
+		chunker string
+		json    bool
+		err     error
+	}{
+		{nil, nil, "default", false, nil},
+		{[]string{"a", "b"}, []string{"a", "b"}, "default", false, nil},
+		{[]string{"-chunker", "rabin", "a"}, []string{"a"}, "rabin", false, nil},
+		{[]string{"a", "-json", "b", "--chunker=buzhash"}, []string{"a", "b"}, "buzhash", true, nil},
+		{[]string{"a", "--", "-json"}, []string{"a", "-json"}, "default", false, nil},
+		{[]string{"-", "-json"}, []string{"-"}, "default", true, nil},
+		{[]string{"a", "-nope"}, nil, "default", false, errUsage},
+		{[]string{"-h"}, nil, "default", false, flag.ErrHelp},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		chunker := fs.String("chunker", "default", "")
+		asJSON := fs.Bool("json", false, "")
+
+		pos, err := parseFlags(fs, tc.args)
+		if err != tc.err {
+			t.Fatalf("%q: expected error %v, got %v", tc.args, tc.err, err)
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(pos, tc.pos) || *chunker != tc.chunker || *asJSON != tc.json {
+			t.Fatalf("%q: got %q, chunker %q, json %v", tc.args, pos, *chunker, *asJSON)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	cid "github.com/ipfs/go-cid"
+	chunk "github.com/ipfs/go-ipfs-chunker"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// Cut reasons.
+const (
+	reasonBoundary = "boundary" // the splitter found a boundary
+	reasonLimit    = "limit"    // the chunk reached the splitter's maximum size
+	reasonEOF      = "eof"      // the input ended
+)
+
+type chunkInfo struct {
+	Offset int64  `json:"offset"`
+	Length int    `json:"length"`
+	Cid    string `json:"cid"`
+	Reason string `json:"reason"`
+}
+
+func splitCmd(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("split", flag.ContinueOnError)
+	chunker := fs.String("chunker", "default", "chunker spec, as accepted by chunk.FromString")
+	asJSON := fs.Bool("json", false, "print one JSON object per chunk")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: ipfs-chunk [split] [flags] [file]\n\n")
+		fmt.Fprintf(fs.Output(), "Splits file (or stdin) and prints the offset, length, raw leaf CID\nand cut reason of every chunk.\n\n")
+		fs.PrintDefaults()
+	}
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) > 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	var path string
+	if len(files) == 1 {
+		path = files[0]
+	}
+	return runSplit(w, path, *chunker, *asJSON)
+}
+
+func runSplit(w io.Writer, path, spec string, asJSON bool) error {
+	f := os.Stdin
+	if path != "" && path != "-" {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+
+	s, err := chunk.FromString(f, spec)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	return split(s, func(c chunkInfo) error {
+		if asJSON {
+			return enc.Encode(c)
+		}
+		_, err := fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", c.Offset, c.Length, c.Cid, c.Reason)
+		return err
+	})
+}
+
+// split reads all chunks from s and calls fn for each of them.
+func split(s chunk.Splitter, fn func(chunkInfo) error) error {
+	max := -1
+	if ms, ok := s.(interface{ MaxChunkSize() int }); ok {
+		max = ms.MaxChunkSize()
+	}
+
+	var off int64
+	var prev *chunkInfo
+	for {
+		b, err := s.NextBytes()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		// The reason of a chunk is only known once we know whether it was the
+		// last one.
+		if prev != nil {
+			if err := fn(*prev); err != nil {
+				return err
+			}
+		}
+
+		c, err := newChunkInfo(off, b)
+		if err != nil {
+			return err
+		}
+		c.Reason = reasonBoundary
+		if len(b) == max {
+			c.Reason = reasonLimit
+		}
+		prev = &c
+		off += int64(len(b))
+	}
+
+	if prev == nil {
+		return nil
+	}
+	if prev.Reason != reasonLimit {
+		prev.Reason = reasonEOF
+	}
+	return fn(*prev)
+}
+
+func newChunkInfo(off int64, b []byte) (chunkInfo, error) {
+	hash, err := mh.Sum(b, mh.SHA2_256, -1)
+	if err != nil {
+		return chunkInfo{}, err
+	}
+	return chunkInfo{
+		Offset: off,
+		Length: len(b),
+		Cid:    cid.NewCidV1(cid.Raw, hash).String(),
+	}, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	chunk "github.com/ipfs/go-ipfs-chunker"
+)
+
+// sliceSplitter returns the chunks of the given sizes.
+type sliceSplitter struct {
+	sizes []int
+	max   int
+}
+
+func (s *sliceSplitter) Reader() io.Reader { return nil }
+
+func (s *sliceSplitter) NextBytes() ([]byte, error) {
+	if len(s.sizes) == 0 {
+		return nil, io.EOF
+	}
+	b := make([]byte, s.sizes[0])
+	s.sizes = s.sizes[1:]
+	return b, nil
+}
+
+func (s *sliceSplitter) MaxChunkSize() int { return s.max }
+
+func TestSplitReasons(t *testing.T) {
+	for _, tc := range []struct {
+		sizes   []int
+		max     int
+		reasons []string
+	}{
+		{nil, 8, nil},
+		{[]int{3}, 8, []string{"eof"}},
+		{[]int{8}, 8, []string{"limit"}},
+		{[]int{5, 8, 2}, 8, []string{"boundary", "limit", "eof"}},
+		{[]int{8, 8}, 8, []string{"limit", "limit"}},
+		{[]int{8, 8}, 0, []string{"boundary", "eof"}},
+	} {
+		var reasons []string
+		var off int64
+		err := split(&sliceSplitter{sizes: tc.sizes, max: tc.max}, func(c chunkInfo) error {
+			if c.Offset != off {
+				t.Fatalf("%v: expected offset %d, got %d", tc.sizes, off, c.Offset)
+			}
+			off += int64(c.Length)
+			reasons = append(reasons, c.Reason)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(reasons, tc.reasons) {
+			t.Fatalf("%v: expected reasons %v, got %v", tc.sizes, tc.reasons, reasons)
+		}
+	}
+}
+
+func TestSplitCmdJSON(t *testing.T) {
+	data := randBuf(t, 1, 10000)
+	paths := writeFiles(t, data)
+
+	var out bytes.Buffer
+	if err := splitCmd(&out, []string{paths[0], "-chunker", "size-4096", "-json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := chunk.FromString(bytes.NewReader(data), "size-4096")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []chunkInfo
+	if err := split(s, func(c chunkInfo) error {
+		want = append(want, c)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []chunkInfo
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var c chunkInfo
+		if err := dec.Decode(&c); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, c)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if len(got) != 3 || got[0].Reason != "limit" || got[2].Reason != "eof" || got[2].Length != 10000-8192 {
+		t.Fatalf("unexpected chunks %+v", got)
+	}
+	c, err := cid.Decode(got[0].Cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version() != 1 || c.Type() != cid.Raw {
+		t.Fatalf("expected a raw leaf CIDv1, got %s", c)
+	}
+}
+
+func TestSplitCmdText(t *testing.T) {
+	paths := writeFiles(t, randBuf(t, 2, 5000))
+
+	var out bytes.Buffer
+	if err := splitCmd(&out, []string{"-chunker=size-4096", paths[0]}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "0\t4096\t") || !strings.HasPrefix(lines[1], "4096\t904\t") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
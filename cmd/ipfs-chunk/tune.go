@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	chunk "github.com/ipfs/go-ipfs-chunker"
+)
+
+func tuneCmd(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("tune", flag.ContinueOnError)
+	objective := fs.String("objective", "dedup", "\"dedup\" to maximize dedup, or \"blocks\" to minimize the number of blocks")
+	minAvg := fs.Int("min-avg", int(chunk.DefaultBlockSize/2), "minimum average chunk size, for the dedup objective")
+	minRatio := fs.Float64("min-ratio", 1.1, "minimum dedup ratio, for the blocks objective")
+	var families specList
+	fs.Var(&families, "family", "chunker family to search, \"rabin\" or \"casync\", may be repeated (default both)")
+	all := fs.Bool("all", false, "print every candidate satisfying the constraint, best first")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: ipfs-chunk tune [flags] path...\n\n")
+		fmt.Fprintf(fs.Output(), "Searches min/avg/max chunk sizes for the given sample corpus and prints\nthe best chunker spec.\n\n")
+		fs.PrintDefaults()
+	}
+	paths, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	opts := chunk.TuneOptions{
+		MinAvgSize: *minAvg,
+		MinRatio:   *minRatio,
+		Families:   families,
+	}
+	switch *objective {
+	case "dedup":
+		opts.Objective = chunk.MaximizeDedup
+	case "blocks":
+		opts.Objective = chunk.MinimizeBlocks
+	default:
+		return fmt.Errorf("unknown objective %q", *objective)
+	}
+
+	files, err := listFiles(paths)
+	if err != nil {
+		return err
+	}
+	samples := make([][]byte, len(files))
+	for i, f := range files {
+		if samples[i], err = os.ReadFile(f); err != nil {
+			return err
+		}
+	}
+
+	results, err := chunk.Tune(samples, opts)
+	if err != nil {
+		return err
+	}
+
+	if !*all {
+		_, err := fmt.Fprintln(w, results[0].Spec)
+		return err
+	}
+	dr := make([]dedupResult, len(results))
+	for i, r := range results {
+		dr[i] = newDedupResult(r.Spec, r.Stats)
+	}
+	return printDedup(w, dr)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	chunk "github.com/ipfs/go-ipfs-chunker"
+)
+
+func TestTuneCmd(t *testing.T) {
+	data := randBuf(t, 5, 1<<20)
+	changed := append(append([]byte{}, data[:500000]...), data[500100:]...)
+	paths := writeFiles(t, data, changed)
+
+	var out bytes.Buffer
+	args := []string{paths[0], paths[1], "-family", "rabin", "-min-avg", "65536"}
+	if err := tuneCmd(&out, args); err != nil {
+		t.Fatal(err)
+	}
+	spec := strings.TrimSpace(out.String())
+	if !strings.HasPrefix(spec, "rabin-") || strings.Contains(spec, "\n") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	if _, err := chunk.FromString(bytes.NewReader(data), spec); err != nil {
+		t.Fatalf("%s: %v", spec, err)
+	}
+
+	out.Reset()
+	args = []string{"-objective", "blocks", "-min-ratio", "1.5", "-family", "casync", "-all", paths[0], paths[1]}
+	if err := tuneCmd(&out, args); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) < 2 || !strings.HasPrefix(strings.Fields(lines[1])[0], "casync-") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestTuneCmdErrors(t *testing.T) {
+	paths := writeFiles(t, randBuf(t, 6, 1000))
+
+	if err := tuneCmd(&bytes.Buffer{}, []string{"-objective", "speed", paths[0]}); err == nil {
+		t.Fatal("expected an error for an unknown objective")
+	}
+	if err := tuneCmd(&bytes.Buffer{}, nil); err != errUsage {
+		t.Fatalf("expected errUsage, got %v", err)
+	}
+}
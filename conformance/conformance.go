@@ -0,0 +1,250 @@
+// Package conformance provides a versioned set of deterministic inputs and
+// the chunk boundaries every chunker spec accepted by chunk.FromString is
+// expected to produce for them.
+//
+// The vectors are stored in vectors.json so that implementations in other
+// languages can use them directly. Each input is described by a generator
+// name, a seed and a size, and can be regenerated with the SplitMix64
+// generator documented on Input; its SHA-256 digest is recorded so that
+// implementations can check they generate the same bytes.
+//
+// The format-aware specs ("tar", "zip", "sqlite", ...) are covered by
+// fixture files in testdata, which hold real archives, a compressed image
+// layer, a database and a Parquet file, and by a fragmented MP4 layout. See
+// testdata/README.md for how they were made.
+//
+// Any change to the recorded boundaries breaks dedup against existing data,
+// and must come with a new Version.
+package conformance
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Version is the version of the vectors. It is bumped whenever the
+// boundaries produced by a spec change.
+const Version = 2
+
+// InputSpec describes how to generate an input.
+type InputSpec struct {
+	Name string `json:"name"`
+	// Generator is one of:
+	//   - "random": Size bytes of SplitMix64 output seeded with Seed.
+	//   - "zero": Size zero bytes.
+	//   - "periodic": the first Period bytes of "random" output seeded
+	//     with Seed, repeated up to Size bytes.
+	//   - "text": lines of SplitMix64 driven words seeded with Seed, see
+	//     Input, truncated to Size bytes.
+	//   - "file": the content of File, a path relative to the conformance
+	//     directory, which must be Size bytes long.
+	Generator string `json:"generator"`
+	Seed      uint64 `json:"seed"`
+	Size      int    `json:"size"`
+	Period    int    `json:"period,omitempty"`
+	File      string `json:"file,omitempty"`
+	// SHA256 is the hex encoded digest of the generated input.
+	SHA256 string `json:"sha256"`
+}
+
+// Vector holds the expected result of chunking an input with a spec.
+type Vector struct {
+	Spec  string `json:"spec"`
+	Input string `json:"input"`
+	// Error is set when the spec is expected to reject the input, either
+	// when the splitter is created or while chunking.
+	Error bool `json:"error,omitempty"`
+	// Boundaries lists the end offset of every chunk.
+	Boundaries []int64 `json:"boundaries"`
+}
+
+// Vectors is the content of vectors.json.
+type Vectors struct {
+	Version int         `json:"version"`
+	Inputs  []InputSpec `json:"inputs"`
+	Vectors []Vector    `json:"vectors"`
+}
+
+//go:embed vectors.json
+var vectorsJSON []byte
+
+//go:embed testdata/*.tar testdata/*.gz testdata/*.zip testdata/*.mp4 testdata/*.sqlite testdata/*.parquet
+var fixtures embed.FS
+
+// Load returns the embedded vectors.
+func Load() (*Vectors, error) {
+	var v Vectors
+	if err := json.Unmarshal(vectorsJSON, &v); err != nil {
+		return nil, err
+	}
+	if v.Version != Version {
+		return nil, fmt.Errorf("vectors.json has version %d, expected %d", v.Version, Version)
+	}
+	return &v, nil
+}
+
+// Input returns the input named name.
+func (v *Vectors) Input(name string) ([]byte, error) {
+	for _, in := range v.Inputs {
+		if in.Name != name {
+			continue
+		}
+		b, err := Generate(in)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(b)
+		if hex.EncodeToString(sum[:]) != in.SHA256 {
+			return nil, fmt.Errorf("input %s does not match its digest", name)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unknown input %s", name)
+}
+
+// SplitMix64 is the generator used to produce the inputs: every call to
+// Next adds 0x9e3779b97f4a7c15 to the state and returns it mixed with the
+// SplitMix64 finalizer. Bytes are taken from each output in little endian
+// order.
+type SplitMix64 struct {
+	state uint64
+}
+
+// NewSplitMix64 returns a generator seeded with seed.
+func NewSplitMix64(seed uint64) *SplitMix64 {
+	return &SplitMix64{state: seed}
+}
+
+// Next returns the next 64 bit output.
+func (g *SplitMix64) Next() uint64 {
+	g.state += 0x9e3779b97f4a7c15
+	z := g.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Read fills b with generator output.
+func (g *SplitMix64) Read(b []byte) {
+	for i := 0; i < len(b); i += 8 {
+		x := g.Next()
+		for j := 0; j < 8 && i+j < len(b); j++ {
+			b[i+j] = byte(x >> (8 * j))
+		}
+	}
+}
+
+var words = []string{
+	"ipfs", "chunk", "block", "merkle", "dag", "content", "address",
+	"hash", "rabin", "buzhash", "boundary", "dedup",
+	"a", "the", "of", "and",
+}
+
+// Generate returns the input described by in. The "text" generator emits
+// words picked by the low 4 bits of successive outputs, separated by a
+// space, with a line break after each word whose output has bits 4 to 7
+// all set.
+func Generate(in InputSpec) ([]byte, error) {
+	b := make([]byte, in.Size)
+	switch in.Generator {
+	case "random":
+		NewSplitMix64(in.Seed).Read(b)
+	case "zero":
+	case "periodic":
+		if in.Period <= 0 {
+			return nil, fmt.Errorf("input %s: invalid period %d", in.Name, in.Period)
+		}
+		p := make([]byte, in.Period)
+		NewSplitMix64(in.Seed).Read(p)
+		for i := 0; i < len(b); i += len(p) {
+			copy(b[i:], p)
+		}
+	case "text":
+		g := NewSplitMix64(in.Seed)
+		b = b[:0]
+		for len(b) < in.Size {
+			x := g.Next()
+			b = append(b, words[x&0xf]...)
+			if x&0xf0 == 0xf0 {
+				b = append(b, '\n')
+			} else {
+				b = append(b, ' ')
+			}
+		}
+		b = b[:in.Size]
+	case "file":
+		f, err := fixtures.ReadFile(in.File)
+		if err != nil {
+			return nil, fmt.Errorf("input %s: %w", in.Name, err)
+		}
+		if len(f) != in.Size {
+			return nil, fmt.Errorf("input %s: %s has %d bytes, expected %d", in.Name, in.File, len(f), in.Size)
+		}
+		b = f
+	default:
+		return nil, fmt.Errorf("input %s: unknown generator %s", in.Name, in.Generator)
+	}
+	return b, nil
+}
+
+// ErrUnsupported can be returned by a SplitFunc for specs it does not
+// implement; the matching vectors are skipped.
+var ErrUnsupported = errors.New("unsupported chunker spec")
+
+// SplitFunc chunks data with spec and returns the end offset of every
+// chunk.
+type SplitFunc func(spec string, data []byte) ([]int64, error)
+
+// Check runs every vector through split and returns an error for each
+// vector whose result does not match.
+func (v *Vectors) Check(split SplitFunc) []error {
+	inputs := make(map[string][]byte)
+	var errs []error
+	for _, vec := range v.Vectors {
+		data, ok := inputs[vec.Input]
+		if !ok {
+			var err error
+			if data, err = v.Input(vec.Input); err != nil {
+				return append(errs, err)
+			}
+			inputs[vec.Input] = data
+		}
+
+		got, err := split(vec.Spec, data)
+		switch {
+		case err == ErrUnsupported:
+		case vec.Error && err == nil:
+			errs = append(errs, fmt.Errorf("%s on %s: expected an error", vec.Spec, vec.Input))
+		case vec.Error:
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s on %s: %w", vec.Spec, vec.Input, err))
+		default:
+			if i := firstMismatch(got, vec.Boundaries); i >= 0 {
+				errs = append(errs, fmt.Errorf("%s on %s: boundary %d differs (got %d chunks, expected %d)",
+					vec.Spec, vec.Input, i, len(got), len(vec.Boundaries)))
+			}
+		}
+	}
+	return errs
+}
+
+// firstMismatch returns the index of the first difference between a and
+// b, or -1 if they are equal.
+func firstMismatch(a, b []int64) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return len(a)
+		}
+		return len(b)
+	}
+	return -1
+}
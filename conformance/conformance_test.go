@@ -0,0 +1,59 @@
+package conformance
+
+import "testing"
+
+func TestSplitMix64(t *testing.T) {
+	// Reference outputs of splitmix64.c seeded with 0.
+	expected := []uint64{0xe220a8397b1dcdaf, 0x6e789e6aa1b965f4, 0x06c45d188009454f}
+
+	g := NewSplitMix64(0)
+	for i, e := range expected {
+		if x := g.Next(); x != e {
+			t.Fatalf("output %d: got %#x, expected %#x", i, x, e)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	v, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range v.Inputs {
+		b, err := v.Input(in.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) != in.Size {
+			t.Fatalf("input %s has %d bytes, expected %d", in.Name, len(b), in.Size)
+		}
+	}
+	for _, vec := range v.Vectors {
+		if !vec.Error && len(vec.Boundaries) > 0 {
+			if last := vec.Boundaries[len(vec.Boundaries)-1]; last == 0 {
+				t.Fatalf("%s on %s: empty chunk", vec.Spec, vec.Input)
+			}
+		}
+	}
+}
+
+func TestCheckMismatch(t *testing.T) {
+	v := &Vectors{
+		Version: Version,
+		Inputs:  []InputSpec{{Name: "zero", Generator: "zero", Size: 4, SHA256: "df3f619804a92fdb4057192dc43dd748ea778adc52bc498ce80524c014b81119"}},
+		Vectors: []Vector{
+			{Spec: "a", Input: "zero", Boundaries: []int64{2, 4}},
+			{Spec: "b", Input: "zero", Boundaries: []int64{4}},
+			{Spec: "c", Input: "zero", Error: true},
+		},
+	}
+	errs := v.Check(func(spec string, data []byte) ([]int64, error) {
+		if spec == "c" {
+			return nil, ErrUnsupported
+		}
+		return []int64{2, 4}, nil
+	})
+	if len(errs) != 1 {
+		t.Fatalf("expected a single mismatch, got %v", errs)
+	}
+}
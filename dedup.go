@@ -0,0 +1,108 @@
+package chunk
+
+import (
+	"crypto/sha256"
+	"io"
+	"time"
+)
+
+// DedupStats summarizes how a chunker deduplicates a set of inputs.
+type DedupStats struct {
+	// TotalBytes and TotalChunks count everything that was chunked.
+	TotalBytes  int64
+	TotalChunks int
+	// UniqueBytes and UniqueChunks only count chunks seen for the first
+	// time, i.e. what would actually be stored.
+	UniqueBytes  int64
+	UniqueChunks int
+	// Duration is the time spent reading and chunking the inputs.
+	Duration time.Duration
+}
+
+// Ratio returns TotalBytes / UniqueBytes, or 1 when nothing was chunked.
+func (s DedupStats) Ratio() float64 {
+	if s.UniqueBytes == 0 {
+		return 1
+	}
+	return float64(s.TotalBytes) / float64(s.UniqueBytes)
+}
+
+// AvgChunkSize returns the average size of the chunks, or 0 when nothing
+// was chunked.
+func (s DedupStats) AvgChunkSize() float64 {
+	if s.TotalChunks == 0 {
+		return 0
+	}
+	return float64(s.TotalBytes) / float64(s.TotalChunks)
+}
+
+// Throughput returns the number of bytes chunked per second.
+func (s DedupStats) Throughput() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.TotalBytes) / s.Duration.Seconds()
+}
+
+// DedupCounter chunks inputs with a FromString spec and keeps track of the
+// chunks seen so far. It is not safe for concurrent use.
+type DedupCounter struct {
+	spec  string
+	seen  map[[sha256.Size]byte]struct{}
+	stats DedupStats
+}
+
+// NewDedupCounter returns a DedupCounter using the given chunker spec. The
+// spec is validated when the first input is added.
+func NewDedupCounter(spec string) *DedupCounter {
+	return &DedupCounter{
+		spec: spec,
+		seen: make(map[[sha256.Size]byte]struct{}),
+	}
+}
+
+// Spec returns the chunker spec of the counter.
+func (dc *DedupCounter) Spec() string {
+	return dc.spec
+}
+
+// Add chunks r and records its chunks. Specs which need to seek, such as
+// "zip", require r to be an io.ReadSeeker.
+func (dc *DedupCounter) Add(r io.Reader) error {
+	start := time.Now()
+	defer func() { dc.stats.Duration += time.Since(start) }()
+
+	s, err := FromString(r, dc.spec)
+	if err != nil {
+		return err
+	}
+
+	for {
+		b, err := s.NextBytes()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		dc.AddChunk(b)
+	}
+}
+
+// AddChunk records a single chunk.
+func (dc *DedupCounter) AddChunk(b []byte) {
+	dc.stats.TotalBytes += int64(len(b))
+	dc.stats.TotalChunks++
+
+	key := sha256.Sum256(b)
+	if _, ok := dc.seen[key]; ok {
+		return
+	}
+	dc.seen[key] = struct{}{}
+	dc.stats.UniqueBytes += int64(len(b))
+	dc.stats.UniqueChunks++
+}
+
+// Stats returns the statistics for everything added so far.
+func (dc *DedupCounter) Stats() DedupStats {
+	return dc.stats
+}
@@ -0,0 +1,60 @@
+package chunk
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestDedupCounter(t *testing.T) {
+	data := randBuf(t, 4<<20)
+
+	dc := NewDedupCounter("size-65536")
+	for i := 0; i < 3; i++ {
+		if err := dc.Add(bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st := dc.Stats()
+	if st.TotalBytes != 3*int64(len(data)) || st.UniqueBytes != int64(len(data)) {
+		t.Fatalf("unexpected byte counts: %+v", st)
+	}
+	if st.TotalChunks != 3*64 || st.UniqueChunks != 64 {
+		t.Fatalf("unexpected chunk counts: %+v", st)
+	}
+	if st.Ratio() != 3 {
+		t.Fatalf("expected a ratio of 3, got %f", st.Ratio())
+	}
+}
+
+func TestDedupCounterShift(t *testing.T) {
+	// A fixed seed keeps the number of chunks lost to the shift stable.
+	data := make([]byte, 8<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+	shifted := append([]byte("inserted"), data...)
+
+	ratio := func(spec string) float64 {
+		dc := NewDedupCounter(spec)
+		for _, in := range [][]byte{data, shifted} {
+			if err := dc.Add(bytes.NewReader(in)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dc.Stats().Ratio()
+	}
+
+	if r := ratio("size-262144"); r > 1.01 {
+		t.Fatalf("fixed size chunks should not survive a shift, got ratio %f", r)
+	}
+	if r := ratio("buzhash"); r < 1.8 {
+		t.Fatalf("buzhash should resynchronize after a shift, got ratio %f", r)
+	}
+}
+
+func TestDedupCounterBadSpec(t *testing.T) {
+	dc := NewDedupCounter("nope")
+	if err := dc.Add(bytes.NewReader(nil)); err == nil {
+		t.Fatal("expected an error")
+	}
+}
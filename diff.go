@@ -0,0 +1,120 @@
+package chunk
+
+import (
+	"crypto/sha256"
+	"io"
+)
+
+// ChunkRef identifies a chunk of a stream.
+type ChunkRef struct {
+	Offset int64
+	Length int
+	Hash   [sha256.Size]byte
+}
+
+// ChunkRefs consumes all chunks from s and returns their references.
+func ChunkRefs(s Splitter) ([]ChunkRef, error) {
+	var refs []ChunkRef
+	var off int64
+	for {
+		b, err := s.NextBytes()
+		if err == io.EOF {
+			return refs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ChunkRef{Offset: off, Length: len(b), Hash: sha256.Sum256(b)})
+		off += int64(len(b))
+	}
+}
+
+// ChunkDiff describes how the chunks of a new version of a stream relate to
+// the chunks of an old version.
+type ChunkDiff struct {
+	// Shared lists the chunks of the new version also found in the old one.
+	Shared []ChunkRef
+	// Inserted lists the chunks of the new version not found in the old one.
+	Inserted []ChunkRef
+	// Removed lists the chunks of the old version not found in the new one.
+	Removed []ChunkRef
+
+	OldSize, NewSize int64
+}
+
+// SharedBytes returns the size of the shared chunks.
+func (d *ChunkDiff) SharedBytes() int64 {
+	return refsSize(d.Shared)
+}
+
+// InsertedBytes returns the size of the inserted chunks.
+func (d *ChunkDiff) InsertedBytes() int64 {
+	return refsSize(d.Inserted)
+}
+
+// RemovedBytes returns the size of the removed chunks.
+func (d *ChunkDiff) RemovedBytes() int64 {
+	return refsSize(d.Removed)
+}
+
+// TransferBytes returns the number of bytes that must be transferred to a
+// peer holding the old version for it to get the new one: the inserted
+// chunks, counting repeated chunks only once.
+func (d *ChunkDiff) TransferBytes() int64 {
+	seen := make(map[[sha256.Size]byte]struct{}, len(d.Inserted))
+	var n int64
+	for _, c := range d.Inserted {
+		if _, ok := seen[c.Hash]; ok {
+			continue
+		}
+		seen[c.Hash] = struct{}{}
+		n += int64(c.Length)
+	}
+	return n
+}
+
+func refsSize(refs []ChunkRef) int64 {
+	var n int64
+	for _, c := range refs {
+		n += int64(c.Length)
+	}
+	return n
+}
+
+// Diff chunks both versions of a stream and compares their chunks. The
+// splitters should be of the same kind for the result to be meaningful.
+func Diff(old, new Splitter) (*ChunkDiff, error) {
+	oldRefs, err := ChunkRefs(old)
+	if err != nil {
+		return nil, err
+	}
+	newRefs, err := ChunkRefs(new)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &ChunkDiff{
+		OldSize: refsSize(oldRefs),
+		NewSize: refsSize(newRefs),
+	}
+
+	inOld := make(map[[sha256.Size]byte]struct{}, len(oldRefs))
+	for _, c := range oldRefs {
+		inOld[c.Hash] = struct{}{}
+	}
+	inNew := make(map[[sha256.Size]byte]struct{}, len(newRefs))
+	for _, c := range newRefs {
+		inNew[c.Hash] = struct{}{}
+		if _, ok := inOld[c.Hash]; ok {
+			d.Shared = append(d.Shared, c)
+		} else {
+			d.Inserted = append(d.Inserted, c)
+		}
+	}
+	for _, c := range oldRefs {
+		if _, ok := inNew[c.Hash]; !ok {
+			d.Removed = append(d.Removed, c)
+		}
+	}
+
+	return d, nil
+}
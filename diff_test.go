@@ -0,0 +1,68 @@
+package chunk
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDiffIdentical(t *testing.T) {
+	data := randBuf(t, 2<<20)
+
+	d, err := Diff(NewBuzhash(bytes.NewReader(data)), NewBuzhash(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Inserted) != 0 || len(d.Removed) != 0 {
+		t.Fatalf("identical inputs should not differ: %d inserted, %d removed", len(d.Inserted), len(d.Removed))
+	}
+	if d.SharedBytes() != int64(len(data)) || d.TransferBytes() != 0 {
+		t.Fatalf("expected everything to be shared, got %d shared, %d to transfer", d.SharedBytes(), d.TransferBytes())
+	}
+}
+
+func TestDiffInsertion(t *testing.T) {
+	old := randBuf(t, 8<<20)
+	insert := randBuf(t, 1000)
+	new := append(append(append([]byte(nil), old[:4<<20]...), insert...), old[4<<20:]...)
+
+	d, err := Diff(NewBuzhash(bytes.NewReader(old)), NewBuzhash(bytes.NewReader(new)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.OldSize != int64(len(old)) || d.NewSize != int64(len(new)) {
+		t.Fatalf("unexpected sizes %d and %d", d.OldSize, d.NewSize)
+	}
+	if d.SharedBytes()+d.InsertedBytes() != d.NewSize {
+		t.Fatal("shared and inserted chunks should cover the new version")
+	}
+	if d.InsertedBytes()-d.RemovedBytes() != int64(len(insert)) {
+		t.Fatalf("inserted and removed chunks should differ by the insertion, got %d and %d", d.InsertedBytes(), d.RemovedBytes())
+	}
+	// Buzhash resynchronizes after the chunk holding the insertion.
+	if len(d.Inserted) > 2 || d.TransferBytes() > 2*buzMax {
+		t.Fatalf("too much to transfer: %d chunks, %d bytes", len(d.Inserted), d.TransferBytes())
+	}
+
+	d, err = Diff(NewSizeSplitter(bytes.NewReader(old), 4096), NewSizeSplitter(bytes.NewReader(new), 4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.SharedBytes() != 4<<20 {
+		t.Fatalf("fixed size chunks should only share the prefix, got %d", d.SharedBytes())
+	}
+}
+
+func TestDiffTransferRepeated(t *testing.T) {
+	d, err := Diff(
+		NewSizeSplitter(bytes.NewReader(make([]byte, 4096)), 1024),
+		NewSizeSplitter(bytes.NewReader(bytes.Repeat([]byte{1}, 4096)), 1024),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Inserted) != 4 || len(d.Removed) != 4 || d.TransferBytes() != 1024 {
+		t.Fatalf("repeated chunks should only be transferred once: %d inserted, %d removed, %d bytes",
+			len(d.Inserted), len(d.Removed), d.TransferBytes())
+	}
+}
@@ -0,0 +1,261 @@
+package chunk
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+var errFuzzRead = errors.New("fuzz read error")
+
+func FuzzFromString(f *testing.F) {
+	for _, spec := range []string{
+		"", "default", "size-1", "size-1-2", "size-", "size-0", "size--1",
+		"rabin", "rabin-0", "rabin-1", "rabin-16-32-64", "rabin-min:16-avg:32-max:64",
+		"rabin-tttd", "rabinx", "buzhash", "buzhash-tttd", "casync",
+		"casync-48-64-128", "rollsum", "rollsum-6", "lines-16", "csv-16",
+		"varint-16", "sparse-16", "tar", "gzip", "zip", "mp4", "sqlite", "parquet",
+	} {
+		f.Add(spec, []byte("hello world\nthis is a test\n"))
+	}
+
+	f.Fuzz(func(t *testing.T, spec string, data []byte) {
+		s, err := FromString(bytes.NewReader(data), spec)
+		if err != nil {
+			return
+		}
+
+		max := ChunkSizeLimit
+		if ms, ok := s.(interface{ MaxChunkSize() int }); ok {
+			max = ms.MaxChunkSize()
+		}
+
+		var got []byte
+		for {
+			b, err := s.NextBytes()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				// Format-aware splitters may reject the input.
+				if !bytes.HasPrefix(data, got) {
+					t.Fatalf("%q: delivered bytes do not match the input", spec)
+				}
+				return
+			}
+			if len(b) == 0 {
+				t.Fatalf("%q: empty chunk", spec)
+			}
+			if len(b) > max {
+				t.Fatalf("%q: %d byte chunk exceeds the max size %d", spec, len(b), max)
+			}
+			got = append(got, b...)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("%q: chunks do not reassemble to the input", spec)
+		}
+	})
+}
+
+var fuzzSplitters = map[string]func(r io.Reader) Splitter{
+	"size":         func(r io.Reader) Splitter { return NewSizeSplitter(r, 1000) },
+	"rabin":        func(r io.Reader) Splitter { return NewRabinMinMax(r, 16, 128, 512) },
+	"rabin-tttd":   func(r io.Reader) Splitter { return mustRabinTTTD(r, 16, 128, 512) },
+	"buzhash":      func(r io.Reader) Splitter { return NewBuzhash(r) },
+	"buzhash-tttd": func(r io.Reader) Splitter { return NewBuzhashTTTD(r) },
+	"casync": func(r io.Reader) Splitter {
+		s, err := NewCasyncMinMax(r, 48, 128, 512)
+		if err != nil {
+			panic(err)
+		}
+		return s
+	},
+	"rollsum":      func(r io.Reader) Splitter { return mustRollsum(r, 6) },
+	"lines":        func(r io.Reader) Splitter { return NewRecordSplitter(r, 64) },
+	"csv":          func(r io.Reader) Splitter { return NewCSVSplitter(r, 64) },
+	"varint":       func(r io.Reader) Splitter { return mustVarintFramed(r, 64) },
+	"tar":          func(r io.Reader) Splitter { return NewTarSplitter(r, DefaultSplitter) },
+	"gzip":         func(r io.Reader) Splitter { return NewGzipSplitter(r, DefaultSplitter) },
+	"mp4":          func(r io.Reader) Splitter { return NewMP4Splitter(r, DefaultSplitter) },
+	"merge":        func(r io.Reader) Splitter { return MergeSmall(mustRollsum(r, 6), 100) },
+	"hierarchical": Hierarchical(DefaultSplitter, func(r io.Reader) Splitter { return mustRollsum(r, 6) }),
+	"sparse": func(r io.Reader) Splitter {
+		s, err := NewSparseSplitter(r, 1000, SizeSplitterGen(300))
+		if err != nil {
+			panic(err)
+		}
+		return s
+	},
+	"aligned": func(r io.Reader) Splitter {
+		s, err := NewAlignedSplitter(r, 512, 2, 100)
+		if err != nil {
+			panic(err)
+		}
+		return s
+	},
+}
+
+// fuzzFormatSplitters may reject their input. The ones needing an
+// io.ReaderAt read data directly and ignore r.
+var fuzzFormatSplitters = map[string]func(data []byte, r io.Reader) (Splitter, error){
+	"zip": func(data []byte, r io.Reader) (Splitter, error) {
+		return NewZipSplitter(bytes.NewReader(data), int64(len(data)), SizeSplitterGen(100))
+	},
+	"parquet": func(data []byte, r io.Reader) (Splitter, error) {
+		return NewParquetSplitter(bytes.NewReader(data), int64(len(data)), SizeSplitterGen(100))
+	},
+	"oci": func(data []byte, r io.Reader) (Splitter, error) {
+		return NewOCILayerSplitter(r)
+	},
+}
+
+// fuzzReader returns a reader over data for the given mode, and the offset
+// at which it fails or -1.
+func fuzzReader(data []byte, mode uint8, at uint16) (io.Reader, int) {
+	var r io.Reader = bytes.NewReader(data)
+	switch mode % 4 {
+	case 1:
+		return iotest.OneByteReader(r), -1
+	case 2:
+		return iotest.HalfReader(r), -1
+	case 3:
+		failAt := int(at)
+		if failAt > len(data) {
+			failAt = len(data)
+		}
+		return io.MultiReader(bytes.NewReader(data[:failAt]), iotest.ErrReader(errFuzzRead)), failAt
+	}
+	return r, -1
+}
+
+func FuzzSplitters(f *testing.F) {
+	f.Add([]byte("hello world\nthis is a test\n"), uint8(0), uint16(0))
+	f.Add(bytes.Repeat([]byte{0}, 4096), uint8(1), uint16(100))
+	f.Add(bytes.Repeat([]byte("a,\"b\nc\",d\n"), 100), uint8(3), uint16(500))
+	for _, seed := range fuzzFormatSeeds(f) {
+		f.Add(seed, uint8(0), uint16(0))
+		f.Add(seed, uint8(3), uint16(len(seed)/2))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte, mode uint8, at uint16) {
+		for name, newSplitter := range fuzzSplitters {
+			r, failAt := fuzzReader(data, mode, at)
+			s := newSplitter(r)
+			var got []byte
+			for {
+				b, err := s.NextBytes()
+				if err == io.EOF {
+					if failAt >= 0 {
+						t.Fatalf("%s: the read error was not returned", name)
+					}
+					break
+				} else if err != nil {
+					if failAt < 0 || !errors.Is(err, errFuzzRead) {
+						t.Fatalf("%s: unexpected error %v", name, err)
+					}
+					if !bytes.Equal(got, data[:failAt]) {
+						t.Fatalf("%s: got %d bytes before the error, expected %d", name, len(got), failAt)
+					}
+					break
+				}
+				if len(b) == 0 {
+					t.Fatalf("%s: empty chunk", name)
+				}
+				got = append(got, b...)
+			}
+			if failAt < 0 && !bytes.Equal(got, data) {
+				t.Fatalf("%s: chunks do not reassemble to the input", name)
+			}
+		}
+
+		for name, newSplitter := range fuzzFormatSplitters {
+			r, failAt := fuzzReader(data, mode, at)
+			s, err := newSplitter(data, r)
+			if err != nil {
+				continue
+			}
+
+			want := data
+			if ls, ok := s.(*OCILayerSplitter); ok && ls.Compressed() {
+				want = fuzzGunzip(data)
+			}
+
+			var got []byte
+			for {
+				b, err := s.NextBytes()
+				if err == io.EOF {
+					if failAt >= 0 && name == "oci" {
+						t.Fatalf("%s: the read error was not returned", name)
+					}
+					if want != nil && !bytes.Equal(got, want) {
+						t.Fatalf("%s: chunks do not reassemble to the input", name)
+					}
+					break
+				} else if err != nil {
+					// The input was rejected, or could not be read.
+					if want != nil && !bytes.HasPrefix(want, got) {
+						t.Fatalf("%s: delivered bytes do not match the input", name)
+					}
+					break
+				}
+				if len(b) == 0 {
+					t.Fatalf("%s: empty chunk", name)
+				}
+				got = append(got, b...)
+			}
+		}
+	})
+}
+
+// fuzzGunzip returns the decompressed data, or nil if it is not valid.
+func fuzzGunzip(data []byte) []byte {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// fuzzFormatSeeds returns small valid inputs for the format splitters.
+func fuzzFormatSeeds(f *testing.F) [][]byte {
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	for _, name := range []string{"a", "b"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		w.Write(bytes.Repeat([]byte(name), 300))
+	}
+	if err := zw.Close(); err != nil {
+		f.Fatal(err)
+	}
+
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	if err := tw.WriteHeader(&tar.Header{Name: "f", Mode: 0o644, Size: 300}); err != nil {
+		f.Fatal(err)
+	}
+	tw.Write(bytes.Repeat([]byte("x"), 300))
+	if err := tw.Close(); err != nil {
+		f.Fatal(err)
+	}
+	var gzBuf bytes.Buffer
+	gw := gzip.NewWriter(&gzBuf)
+	gw.Write(tarBuf.Bytes())
+	if err := gw.Close(); err != nil {
+		f.Fatal(err)
+	}
+
+	parquet, _ := makeParquet(f, [][]int{{50, 30}, {20}})
+
+	return [][]byte{zipBuf.Bytes(), gzBuf.Bytes(), parquet}
+}
@@ -2,9 +2,11 @@ module github.com/ipfs/go-ipfs-chunker
 
 require (
 	github.com/ipfs/go-block-format v0.0.2
+	github.com/ipfs/go-cid v0.0.1
 	github.com/ipfs/go-ipfs-util v0.0.1
 	github.com/ipfs/go-log v0.0.1
 	github.com/libp2p/go-buffer-pool v0.0.2
+	github.com/multiformats/go-multihash v0.0.1
 	github.com/whyrusleeping/chunker v0.0.0-20181014151217-fe64bd25879f
 )
 
@@ -12,7 +14,6 @@ require (
 	github.com/gogo/protobuf v1.2.1 // indirect
 	github.com/gxed/hashland/keccakpg v0.0.1 // indirect
 	github.com/gxed/hashland/murmur3 v0.0.1 // indirect
-	github.com/ipfs/go-cid v0.0.1 // indirect
 	github.com/mattn/go-colorable v0.1.1 // indirect
 	github.com/mattn/go-isatty v0.0.5 // indirect
 	github.com/minio/blake2b-simd v0.0.0-20160723061019-3f5f724cb5b1 // indirect
@@ -20,7 +21,6 @@ require (
 	github.com/mr-tron/base58 v1.1.0 // indirect
 	github.com/multiformats/go-base32 v0.0.3 // indirect
 	github.com/multiformats/go-multibase v0.0.1 // indirect
-	github.com/multiformats/go-multihash v0.0.1 // indirect
 	github.com/opentracing/opentracing-go v1.0.2 // indirect
 	github.com/whyrusleeping/go-logging v0.0.0-20170515211332-0457bb6b88fc // indirect
 	golang.org/x/crypto v0.0.0-20190211182817-74369b46fc67 // indirect
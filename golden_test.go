@@ -0,0 +1,158 @@
+package chunk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ipfs/go-ipfs-chunker/conformance"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "regenerate conformance/vectors.json")
+
+var goldenInputs = []conformance.InputSpec{
+	{Name: "empty", Generator: "zero", Size: 0},
+	{Name: "small", Generator: "random", Seed: 1, Size: 100},
+	{Name: "random", Generator: "random", Seed: 2, Size: 3<<20 + 17},
+	{Name: "zero", Generator: "zero", Size: 1<<20 + 513},
+	{Name: "periodic", Generator: "periodic", Seed: 3, Size: 2 << 20, Period: 40009},
+	{Name: "text", Generator: "text", Seed: 4, Size: 1 << 20},
+	// Size is filled in from the fixture files.
+	{Name: "tar", Generator: "file", File: "testdata/archive.tar"},
+	{Name: "oci-layer", Generator: "file", File: "testdata/layer.tar.gz"},
+	{Name: "gzip-members", Generator: "file", File: "testdata/members.gz"},
+	{Name: "zip", Generator: "file", File: "testdata/archive.zip"},
+	{Name: "mp4", Generator: "file", File: "testdata/video.mp4"},
+	{Name: "sqlite", Generator: "file", File: "testdata/database.sqlite"},
+	{Name: "parquet", Generator: "file", File: "testdata/data.parquet"},
+}
+
+var goldenSpecs = []string{
+	"default",
+	"size-1024",
+	"size-262144",
+	"rabin",
+	"rabin-65536",
+	"rabin-16384-65536-262144",
+	"rabin-tttd",
+	"buzhash",
+	"buzhash-tttd",
+	"casync",
+	"casync-1024-4096-16384",
+	"rollsum",
+	"rollsum-10",
+	"lines-4096",
+	"csv-4096",
+	"varint-65536",
+	"sparse-65536",
+	"tar",
+	"gzip",
+	"mp4",
+	"zip",
+	"sqlite",
+	"parquet",
+}
+
+func goldenSplit(spec string, data []byte) ([]int64, error) {
+	s, err := FromString(bytes.NewReader(data), spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var ends []int64
+	var off int64
+	for {
+		b, err := s.NextBytes()
+		if err == io.EOF {
+			return ends, nil
+		} else if err != nil {
+			return nil, err
+		}
+		off += int64(len(b))
+		ends = append(ends, off)
+	}
+}
+
+func TestGoldenVectors(t *testing.T) {
+	if *updateGolden {
+		writeGolden(t)
+	}
+
+	v, err := conformance.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Vectors) != len(goldenInputs)*len(goldenSpecs) {
+		t.Fatalf("vectors.json is out of date, run go test -run TestGoldenVectors -update-golden")
+	}
+	for _, err := range v.Check(goldenSplit) {
+		t.Error(err)
+	}
+}
+
+func writeGolden(t *testing.T) {
+	v := conformance.Vectors{Version: conformance.Version}
+	for _, in := range goldenInputs {
+		if in.Generator == "file" {
+			fi, err := os.Stat(filepath.Join("conformance", in.File))
+			if err != nil {
+				t.Fatal(err)
+			}
+			in.Size = int(fi.Size())
+		}
+		data, err := conformance.Generate(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(data)
+		in.SHA256 = hex.EncodeToString(sum[:])
+		v.Inputs = append(v.Inputs, in)
+
+		for _, spec := range goldenSpecs {
+			ends, err := goldenSplit(spec, data)
+			if ends == nil {
+				ends = []int64{}
+			}
+			v.Vectors = append(v.Vectors, conformance.Vector{
+				Spec:       spec,
+				Input:      in.Name,
+				Error:      err != nil,
+				Boundaries: ends,
+			})
+		}
+	}
+
+	// One vector per line keeps diffs of vectors.json readable.
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "{\n\"version\": %d,\n\"inputs\": [\n", v.Version)
+	writeJSONLines(t, &buf, len(v.Inputs), func(i int) interface{} { return v.Inputs[i] })
+	buf.WriteString("],\n\"vectors\": [\n")
+	writeJSONLines(t, &buf, len(v.Vectors), func(i int) interface{} { return v.Vectors[i] })
+	buf.WriteString("]\n}\n")
+
+	if err := os.WriteFile("conformance/vectors.json", buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Skip("regenerated conformance/vectors.json, run the tests again")
+}
+
+func writeJSONLines(t *testing.T, buf *bytes.Buffer, n int, elem func(int) interface{}) {
+	for i := 0; i < n; i++ {
+		b, err := json.Marshal(elem(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(b)
+		if i < n-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+}
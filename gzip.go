@@ -0,0 +1,248 @@
+package chunk
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"hash/fnv"
+	"io"
+)
+
+const (
+	// gzipResetWindow is the number of compressed bytes before a reset
+	// point which decide whether a chunk ends there.
+	gzipResetWindow = 32
+	// gzipResetSpacing is the average number of reset points between two
+	// chunk boundaries within a member.
+	gzipResetSpacing = 64
+)
+
+// gzipResetMarker is the empty stored block which ends a sync or full flush.
+var gzipResetMarker = []byte{0x00, 0x00, 0xff, 0xff}
+
+// GzipSplitter implements the Splitter interface for gzip streams. Chunk
+// boundaries are aligned to the start of every member of a stream made of
+// concatenated members, as written by appending gzip files. Within a
+// member, chunks may also end at the reset points written by
+// gzip --rsyncable and pigz --rsyncable, which follow a flush to a byte
+// boundary and its empty stored block (00 00 ff ff). One in about
+// gzipResetSpacing reset points is used, picked by a hash of the compressed
+// bytes before it. The compressed bytes between two boundaries are split
+// separately by an inner splitter, so that members, or regions of an
+// rsyncable member, which did not change between two versions of a file
+// produce the same chunks.
+//
+// Reset points are found by their byte pattern, which could also occur
+// within compressed data. Such a match only moves a chunk boundary.
+//
+// Members are decompressed only to find where they end. Data that is not a
+// valid gzip member, and everything after it, is handed to the inner
+// splitter unchanged.
+type GzipSplitter struct {
+	r     io.Reader
+	trap  *readTrap
+	br    *bufio.Reader
+	inner SplitterGen
+
+	zr     *gzip.Reader
+	member *gzipMember
+	cur    Splitter
+
+	err error
+}
+
+// NewGzipSplitter returns a GzipSplitter which splits each member with the
+// given inner splitter.
+func NewGzipSplitter(r io.Reader, inner SplitterGen) *GzipSplitter {
+	trap := newReadTrap(r)
+	return &GzipSplitter{
+		r:     r,
+		trap:  trap,
+		br:    bufio.NewReader(trap),
+		inner: inner,
+	}
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (gs *GzipSplitter) Reader() io.Reader {
+	return gs.r
+}
+
+// NextBytes produces a new chunk.
+func (gs *GzipSplitter) NextBytes() ([]byte, error) {
+	return gs.trap.result(gs.nextBytes())
+}
+
+func (gs *GzipSplitter) nextBytes() ([]byte, error) {
+	if gs.err != nil {
+		return nil, gs.err
+	}
+
+	for {
+		if gs.cur != nil {
+			b, err := gs.cur.NextBytes()
+			if err == nil {
+				return b, nil
+			} else if err != io.EOF {
+				gs.err = err
+				return nil, err
+			}
+			gs.cur = nil
+			if m := gs.member; m != nil && m.paused {
+				// The member goes on after a reset point.
+				m.paused = false
+				gs.cur = gs.inner(m)
+				continue
+			}
+			gs.member = nil
+		}
+
+		magic, err := gs.br.Peek(3)
+		if len(magic) == 0 {
+			if err == nil {
+				err = io.EOF
+			}
+			gs.err = err
+			return nil, err
+		}
+
+		if !bytes.Equal(magic, []byte{0x1f, 0x8b, 0x08}) {
+			gs.cur = gs.inner(gs.br)
+			continue
+		}
+		gs.member = gs.newMember()
+		gs.cur = gs.inner(gs.member)
+	}
+}
+
+func (gs *GzipSplitter) newMember() *gzipMember {
+	m := &gzipMember{
+		src: &recordingReader{br: gs.br},
+	}
+
+	var err error
+	if gs.zr == nil {
+		gs.zr, err = gzip.NewReader(m.src)
+	} else {
+		err = gs.zr.Reset(m.src)
+	}
+	if err != nil {
+		m.failed = true
+		return m
+	}
+	gs.zr.Multistream(false)
+	m.zr = gs.zr
+	return m
+}
+
+// recordingReader is a flate.Reader which keeps a copy of every byte read
+// through it, so that gzip.Reader reads no further than the end of the
+// member and the compressed bytes can be handed out again.
+type recordingReader struct {
+	br  *bufio.Reader
+	rec bytes.Buffer
+}
+
+func (rr *recordingReader) Read(p []byte) (int, error) {
+	n, err := rr.br.Read(p)
+	rr.rec.Write(p[:n])
+	return n, err
+}
+
+func (rr *recordingReader) ReadByte() (byte, error) {
+	b, err := rr.br.ReadByte()
+	if err == nil {
+		rr.rec.WriteByte(b)
+	}
+	return b, err
+}
+
+// gzipMember reads the compressed bytes of a single gzip member. If the
+// member turns out to be invalid, it reads on until the end of the stream.
+// It returns io.EOF early, with paused set, after a reset point where a
+// chunk ends.
+type gzipMember struct {
+	src     *recordingReader
+	zr      *gzip.Reader
+	scratch [32 << 10]byte
+
+	// hist holds the last bytes read, to find reset points spanning reads.
+	hist []byte
+
+	done   bool
+	failed bool
+	paused bool
+}
+
+func (m *gzipMember) Read(p []byte) (int, error) {
+	if m.paused {
+		return 0, io.EOF
+	}
+	for m.src.rec.Len() == 0 && !m.done && !m.failed {
+		_, err := m.zr.Read(m.scratch[:])
+		if err == io.EOF {
+			m.done = true
+		} else if err != nil {
+			m.failed = true
+		}
+	}
+
+	if m.src.rec.Len() > 0 {
+		b := m.src.rec.Bytes()
+		if len(b) > len(p) {
+			b = b[:len(p)]
+		}
+		if !m.failed {
+			if i := m.resetCut(b); i >= 0 {
+				b = b[:i]
+				m.paused = true
+			}
+		}
+		n := copy(p, b)
+		m.src.rec.Next(n)
+		m.keep(p[:n])
+		return n, nil
+	}
+	if m.failed {
+		return m.src.br.Read(p)
+	}
+	return 0, io.EOF
+}
+
+// resetCut returns the length of b up to the end of the first reset point
+// in it where a chunk ends, or -1.
+func (m *gzipMember) resetCut(b []byte) int {
+	buf := append(m.hist[:len(m.hist):len(m.hist)], b...)
+	for off := 0; ; {
+		i := bytes.Index(buf[off:], gzipResetMarker)
+		if i < 0 {
+			return -1
+		}
+		i += off
+		off = i + 1
+
+		end := i + len(gzipResetMarker) - len(m.hist)
+		if end <= 0 || i < gzipResetWindow {
+			// Already seen, or too close to the start of the member.
+			continue
+		}
+		h := fnv.New32a()
+		h.Write(buf[i-gzipResetWindow : i])
+		if h.Sum32()%gzipResetSpacing == 0 {
+			return end
+		}
+	}
+}
+
+// keep records the tail of the bytes read for resetCut.
+func (m *gzipMember) keep(b []byte) {
+	const size = gzipResetWindow + 3
+	if len(b) >= size {
+		m.hist = append(m.hist[:0], b[len(b)-size:]...)
+		return
+	}
+	m.hist = append(m.hist, b...)
+	if len(m.hist) > size {
+		m.hist = append(m.hist[:0], m.hist[len(m.hist)-size:]...)
+	}
+}
@@ -0,0 +1,132 @@
+package chunk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func gzipMembers(t *testing.T, parts ...[]byte) ([]byte, []int) {
+	var buf bytes.Buffer
+	var starts []int
+	for _, part := range parts {
+		starts = append(starts, buf.Len())
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(part); err != nil {
+			t.Fatal(err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes(), starts
+}
+
+func chunkStarts(chunks [][]byte) map[int]bool {
+	starts := make(map[int]bool)
+	var off int
+	for _, chunk := range chunks {
+		starts[off] = true
+		off += len(chunk)
+	}
+	return starts
+}
+
+func TestGzipSplitter(t *testing.T) {
+	text := makeLines(20000)
+	parts := [][]byte{text[:300000], randBuf(t, 500000), text[300000:], {}}
+
+	data, members := gzipMembers(t, parts...)
+	chunks := splitAll(t, NewGzipSplitter(bytes.NewReader(data), SizeSplitterGen(64<<10)))
+
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+	starts := chunkStarts(chunks)
+	for i, m := range members {
+		if !starts[m] {
+			t.Fatalf("member %d at %d does not start a chunk", i, m)
+		}
+	}
+
+	// Changing one member must not affect the chunks of the others.
+	parts[0] = append([]byte("changed"), parts[0]...)
+	changed, _ := gzipMembers(t, parts...)
+	chunks2 := splitAll(t, NewGzipSplitter(bytes.NewReader(changed), SizeSplitterGen(64<<10)))
+
+	seen := make(map[string]bool)
+	for _, chunk := range chunks {
+		seen[string(chunk)] = true
+	}
+	var common int
+	for _, chunk := range chunks2 {
+		if seen[string(chunk)] {
+			common += len(chunk)
+		}
+	}
+	if common < len(data)-members[1] {
+		t.Fatalf("expected unchanged members to dedup, only %d bytes in common", common)
+	}
+}
+
+func TestGzipSplitterInvalid(t *testing.T) {
+	good, _ := gzipMembers(t, makeLines(1000))
+
+	for name, data := range map[string][]byte{
+		"random":    randBuf(t, 1<<20),
+		"truncated": good[:len(good)/2],
+		"trailing":  append(append([]byte{}, good...), make([]byte, 1000)...),
+		"corrupt":   append(append([]byte{}, good[:100]...), randBuf(t, 1<<16)...),
+		"badheader": append([]byte{0x1f, 0x8b, 0x08, 0xff}, randBuf(t, 1000)...),
+	} {
+		chunks := splitAll(t, NewGzipSplitter(bytes.NewReader(data), SizeSplitterGen(4096)))
+		if !bytes.Equal(bytes.Join(chunks, nil), data) {
+			t.Fatalf("%s: data was chunked incorrectly", name)
+		}
+	}
+}
+
+func TestGzipSplitterRsyncable(t *testing.T) {
+	// Flush writes the same empty stored block as gzip --rsyncable does at
+	// its reset points.
+	text := makeLines(40000)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	for i := 0; i < len(text); i += 4096 {
+		end := i + 4096
+		if end > len(text) {
+			end = len(text)
+		}
+		if _, err := zw.Write(text[i:end]); err != nil {
+			t.Fatal(err)
+		}
+		if err := zw.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	chunks := splitAll(t, NewGzipSplitter(bytes.NewReader(data), DefaultSplitter))
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+
+	var resets, off int
+	for _, chunk := range chunks[:len(chunks)-1] {
+		off += len(chunk)
+		if len(chunk) == int(DefaultBlockSize) {
+			continue
+		}
+		if !bytes.Equal(data[off-len(gzipResetMarker):off], gzipResetMarker) {
+			t.Fatalf("chunk ending at %d does not end at a reset point", off)
+		}
+		resets++
+	}
+	if resets == 0 {
+		t.Fatal("no chunk ended at a reset point")
+	}
+	t.Logf("%d chunks, %d ending at reset points", len(chunks), resets)
+}
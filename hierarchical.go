@@ -0,0 +1,81 @@
+package chunk
+
+import (
+	"bytes"
+	"io"
+)
+
+// HierarchicalSplitter implements the Splitter interface with two levels
+// of chunking: the outer splitter cuts the stream into super-chunks, each of
+// which is then split again by the inner splitter. Every chunk is
+// annotated with the index of the super-chunk it belongs to, so that a DAG
+// builder can add an intermediate layer along the super-chunk boundaries.
+type HierarchicalSplitter struct {
+	r     io.Reader
+	outer Splitter
+	inner SplitterGen
+
+	cur   Splitter
+	super int
+
+	err error
+}
+
+// Hierarchical returns a SplitterGen which creates HierarchicalSplitters
+// using the given outer and inner splitters.
+func Hierarchical(outer, inner SplitterGen) SplitterGen {
+	return func(r io.Reader) Splitter {
+		return NewHierarchicalSplitter(r, outer, inner)
+	}
+}
+
+// NewHierarchicalSplitter returns a HierarchicalSplitter reading from r.
+func NewHierarchicalSplitter(r io.Reader, outer, inner SplitterGen) *HierarchicalSplitter {
+	return &HierarchicalSplitter{
+		r:     r,
+		outer: outer(r),
+		inner: inner,
+		super: -1,
+	}
+}
+
+// NextChunk produces a new chunk along with the index of the super-chunk
+// it belongs to. Indexes start at 0 and increase by one for every
+// super-chunk.
+func (hs *HierarchicalSplitter) NextChunk() ([]byte, int, error) {
+	if hs.err != nil {
+		return nil, hs.super, hs.err
+	}
+
+	for {
+		if hs.cur != nil {
+			b, err := hs.cur.NextBytes()
+			if err == nil {
+				return b, hs.super, nil
+			} else if err != io.EOF {
+				hs.err = err
+				return nil, hs.super, err
+			}
+			hs.cur = nil
+		}
+
+		super, err := hs.outer.NextBytes()
+		if err != nil {
+			hs.err = err
+			return nil, hs.super, err
+		}
+		hs.super++
+		hs.cur = hs.inner(bytes.NewReader(super))
+	}
+}
+
+// NextBytes produces a new chunk.
+func (hs *HierarchicalSplitter) NextBytes() ([]byte, error) {
+	b, _, err := hs.NextChunk()
+	return b, err
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (hs *HierarchicalSplitter) Reader() io.Reader {
+	return hs.r
+}
@@ -0,0 +1,51 @@
+package chunk
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestHierarchical(t *testing.T) {
+	data := randBuf(t, 4<<20)
+
+	supers := splitAll(t, NewBuzhash(bytes.NewReader(data)))
+
+	gen := Hierarchical(func(r io.Reader) Splitter {
+		return NewBuzhash(r)
+	}, SizeSplitterGen(4096))
+	hs := gen(bytes.NewReader(data)).(*HierarchicalSplitter)
+
+	var chunks [][]byte
+	sizes := make([]int, len(supers))
+	for {
+		b, super, err := hs.NextChunk()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		if len(b) > 4096 {
+			t.Fatalf("chunk of %d bytes exceeds the inner splitter size", len(b))
+		}
+		if super >= len(supers) {
+			t.Fatalf("unexpected super-chunk index %d", super)
+		}
+		sizes[super] += len(b)
+		chunks = append(chunks, b)
+	}
+
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+	for i, super := range supers {
+		if sizes[i] != len(super) {
+			t.Fatalf("super-chunk %d: expected %d bytes, got %d", i, len(super), sizes[i])
+		}
+	}
+
+	if _, err := hs.NextBytes(); err != io.EOF {
+		t.Fatalf("expected io.EOF after the last chunk, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package chunk
+
+import (
+	"io"
+)
+
+type mergeSplitter struct {
+	s       Splitter
+	minSize int
+
+	pending [][]byte
+	err     error
+}
+
+// MergeSmall returns a Splitter which coalesces the chunks produced by s
+// that are smaller than minSize with their neighbor, as long as the result
+// does not exceed ChunkSizeLimit. Chunks are merged with the ones that
+// follow them until they reach minSize, and a short tail at the end of the
+// input is merged with the previous chunk.
+func MergeSmall(s Splitter, minSize int) Splitter {
+	return &mergeSplitter{
+		s:       s,
+		minSize: minSize,
+	}
+}
+
+// peek returns the i-th chunk following the current one, reading it from
+// the underlying splitter if needed.
+func (ms *mergeSplitter) peek(i int) ([]byte, bool) {
+	for len(ms.pending) <= i {
+		if ms.err != nil {
+			return nil, false
+		}
+		b, err := ms.s.NextBytes()
+		if err != nil {
+			// Deliver what we have, the error is returned on the next call.
+			ms.err = err
+			return nil, false
+		}
+		ms.pending = append(ms.pending, b)
+	}
+	return ms.pending[i], true
+}
+
+// NextBytes produces a new chunk.
+func (ms *mergeSplitter) NextBytes() ([]byte, error) {
+	cur, ok := ms.peek(0)
+	if !ok {
+		return nil, ms.err
+	}
+	ms.pending = ms.pending[1:]
+
+	for len(cur) < ms.minSize {
+		next, ok := ms.peek(0)
+		if !ok || len(cur)+len(next) > ChunkSizeLimit {
+			return cur, nil
+		}
+		cur = append(cur, next...)
+		ms.pending = ms.pending[1:]
+	}
+
+	// Look for a short tail, which would otherwise be left unmerged.
+	tail := 0
+	for i := 0; tail < ms.minSize; i++ {
+		next, ok := ms.peek(i)
+		if !ok {
+			if len(cur)+tail <= ChunkSizeLimit {
+				for _, b := range ms.pending {
+					cur = append(cur, b...)
+				}
+				ms.pending = nil
+			}
+			break
+		}
+		tail += len(next)
+	}
+
+	return cur, nil
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (ms *mergeSplitter) Reader() io.Reader {
+	return ms.s.Reader()
+}
@@ -0,0 +1,109 @@
+package chunk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+type sliceSplitter struct {
+	chunks [][]byte
+	err    error
+}
+
+func (ss *sliceSplitter) NextBytes() ([]byte, error) {
+	if len(ss.chunks) == 0 {
+		return nil, ss.err
+	}
+	b := ss.chunks[0]
+	ss.chunks = ss.chunks[1:]
+	return b, nil
+}
+
+func (ss *sliceSplitter) Reader() io.Reader {
+	return nil
+}
+
+func chunkSizes(chunks [][]byte) []int {
+	sizes := make([]int, len(chunks))
+	for i, chunk := range chunks {
+		sizes[i] = len(chunk)
+	}
+	return sizes
+}
+
+func TestMergeSmall(t *testing.T) {
+	mk := func(sizes ...int) [][]byte {
+		chunks := make([][]byte, len(sizes))
+		for i, size := range sizes {
+			chunks[i] = make([]byte, size)
+		}
+		return chunks
+	}
+
+	for _, tc := range []struct {
+		in, out []int
+	}{
+		{[]int{100, 100, 10}, []int{100, 110}},
+		{[]int{10, 10, 100}, []int{120}},
+		{[]int{100, 5, 100, 100}, []int{100, 105, 100}},
+		{[]int{100, 5, 5}, []int{110}},
+		{[]int{20, 20, 20, 20, 20, 20, 20, 20, 20, 20, 20, 20, 20, 20, 20, 20, 20, 20, 20, 20}, []int{60, 60, 60, 60, 60, 100}},
+		{[]int{5}, []int{5}},
+		{[]int{ChunkSizeLimit, 10}, []int{ChunkSizeLimit, 10}},
+		{[]int{ChunkSizeLimit - 10, 10}, []int{ChunkSizeLimit}},
+	} {
+		s := MergeSmall(&sliceSplitter{chunks: mk(tc.in...), err: io.EOF}, 50)
+		got := chunkSizes(splitAll(t, s))
+		if len(got) != len(tc.out) {
+			t.Fatalf("%v: expected %v, got %v", tc.in, tc.out, got)
+		}
+		for i := range got {
+			if got[i] != tc.out[i] {
+				t.Fatalf("%v: expected %v, got %v", tc.in, tc.out, got)
+			}
+		}
+	}
+}
+
+func TestMergeSmallFixedSize(t *testing.T) {
+	data := randBuf(t, 3<<20)
+	chunks := splitAll(t, MergeSmall(NewSizeSplitter(bytes.NewReader(data), 4096), 16384))
+
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+	for i, chunk := range chunks {
+		if len(chunk) >= 2*16384 {
+			t.Fatalf("chunk %d/%d is too large: %d bytes", i+1, len(chunks), len(chunk))
+		}
+	}
+}
+
+func TestMergeSmallDeliversBeforeError(t *testing.T) {
+	errBoom := errors.New("boom")
+	s := MergeSmall(&sliceSplitter{chunks: [][]byte{{1}, {2}}, err: errBoom}, 50)
+
+	b, err := s.NextBytes()
+	if err != nil || !bytes.Equal(b, []byte{1, 2}) {
+		t.Fatalf("expected merged chunk, got %v, %v", b, err)
+	}
+	if _, err := s.NextBytes(); err != errBoom {
+		t.Fatalf("expected errBoom, got %v", err)
+	}
+}
+
+func TestMergeSmallRabin(t *testing.T) {
+	data := randBuf(t, 1<<20)
+	chunks := splitAll(t, MergeSmall(NewRabinMinMax(bytes.NewReader(data), 16, 1024, 4096), 512))
+
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+	for i, chunk := range chunks {
+		if len(chunk) < 512 && len(chunks) > 1 {
+			t.Fatalf("chunk %d/%d was not merged: %d bytes", i+1, len(chunks), len(chunk))
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package chunk
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics receives measurements from the splitters created with
+// Instrumented. Implementations must be safe for concurrent use.
+type Metrics interface {
+	// Chunk is called for every chunk produced.
+	Chunk(size int)
+	// Read is called after every read from the input with the number of
+	// bytes read and the time the read took.
+	Read(n int, d time.Duration)
+	// Split is called after every call to NextBytes with the time spent in
+	// the splitter itself, not counting the reads.
+	Split(d time.Duration)
+}
+
+// Instrumented returns a SplitterGen reporting the activity of the
+// splitters created by gen to m.
+func Instrumented(gen SplitterGen, m Metrics) SplitterGen {
+	return func(r io.Reader) Splitter {
+		return NewInstrumentedSplitter(r, gen, m)
+	}
+}
+
+// NewInstrumentedSplitter splits r with the splitter returned by gen and
+// reports its activity to m. When r implements io.ReaderAt and io.Seeker,
+// so does the reader passed to gen.
+func NewInstrumentedSplitter(r io.Reader, gen SplitterGen, m Metrics) Splitter {
+	tr := &timedReader{r: r, m: m}
+	var in io.Reader = tr
+	if ra, ok := r.(interface {
+		io.ReaderAt
+		io.Seeker
+	}); ok {
+		in = &timedReadSeekerAt{timedReader: tr, ra: ra}
+	}
+
+	return &instrumentedSplitter{
+		r:  r,
+		tr: tr,
+		s:  gen(in),
+		m:  m,
+	}
+}
+
+type instrumentedSplitter struct {
+	r  io.Reader
+	tr *timedReader
+	s  Splitter
+	m  Metrics
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (is *instrumentedSplitter) Reader() io.Reader {
+	return is.r
+}
+
+// NextBytes produces a new chunk.
+func (is *instrumentedSplitter) NextBytes() ([]byte, error) {
+	is.tr.spent = 0
+	start := time.Now()
+	b, err := is.s.NextBytes()
+	is.m.Split(time.Since(start) - is.tr.spent)
+	if err == nil {
+		is.m.Chunk(len(b))
+	}
+	return b, err
+}
+
+// timedReader reports every read to m, and sums the time spent reading.
+type timedReader struct {
+	r     io.Reader
+	m     Metrics
+	spent time.Duration
+}
+
+func (tr *timedReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := tr.r.Read(p)
+	tr.report(n, time.Since(start))
+	return n, err
+}
+
+func (tr *timedReader) report(n int, d time.Duration) {
+	tr.spent += d
+	tr.m.Read(n, d)
+}
+
+type timedReadSeekerAt struct {
+	*timedReader
+	ra interface {
+		io.ReaderAt
+		io.Seeker
+	}
+}
+
+func (tr *timedReadSeekerAt) ReadAt(p []byte, off int64) (int, error) {
+	start := time.Now()
+	n, err := tr.ra.ReadAt(p, off)
+	tr.report(n, time.Since(start))
+	return n, err
+}
+
+func (tr *timedReadSeekerAt) Seek(offset int64, whence int) (int64, error) {
+	return tr.ra.Seek(offset, whence)
+}
+
+// PoolMetrics receives the buffer pool usage of the splitters of this
+// package. Implementations must be safe for concurrent use.
+type PoolMetrics interface {
+	// PoolGet is called whenever a splitter takes a buffer from the pool
+	// of this package, reporting whether the buffer was reused.
+	PoolGet(hit bool)
+}
+
+var poolMetrics atomic.Value // poolMetricsValue
+
+// poolMetricsValue wraps the PoolMetrics, as an atomic.Value only stores
+// values of a single concrete type.
+type poolMetricsValue struct {
+	m PoolMetrics
+}
+
+// SetPoolMetrics sets where the buffer pool usage of all the splitters of
+// this package is reported, or stops reporting it if m is nil.
+//
+// The splitters recycle their buffers in a pool of their own, so that
+// chunks put back into go-buffer-pool by their users are not reused by the
+// splitters, and do not count as hits.
+func SetPoolMetrics(m PoolMetrics) {
+	poolMetrics.Store(poolMetricsValue{m})
+}
+
+// getBuf takes a buffer from the pool, reporting it to the PoolMetrics if
+// any.
+func getBuf(size int) []byte {
+	b, hit := bufPool.get(size)
+	if pm, _ := poolMetrics.Load().(poolMetricsValue); pm.m != nil && b != nil {
+		pm.m.PoolGet(hit)
+	}
+	return b
+}
+
+// putBuf puts a buffer taken with getBuf back into the pool.
+func putBuf(b []byte) {
+	bufPool.put(b)
+}
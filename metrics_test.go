@@ -0,0 +1,155 @@
+package chunk
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	pool "github.com/libp2p/go-buffer-pool"
+)
+
+type testMetrics struct {
+	mu                sync.Mutex
+	chunks, chunkSize int
+	reads, readBytes  int
+	splits            int
+	readTime          time.Duration
+	hits, misses      int
+}
+
+func (m *testMetrics) Chunk(size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chunks++
+	m.chunkSize += size
+}
+
+func (m *testMetrics) Read(n int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reads++
+	m.readBytes += n
+	m.readTime += d
+}
+
+func (m *testMetrics) Split(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.splits++
+}
+
+func (m *testMetrics) PoolGet(hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.hits++
+	} else {
+		m.misses++
+	}
+}
+
+// slowReader sleeps before every read.
+type slowReader struct {
+	r io.Reader
+}
+
+func (s slowReader) Read(p []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	return s.r.Read(p)
+}
+
+func TestInstrumented(t *testing.T) {
+	data := randBuf(t, 3<<20+100)
+	m := &testMetrics{}
+
+	gen := Instrumented(func(r io.Reader) Splitter { return NewBuzhash(r) }, m)
+	r := slowReader{bytes.NewReader(data)}
+	s := gen(r)
+	if s.Reader() != io.Reader(r) {
+		t.Fatal("Reader does not return the original reader")
+	}
+
+	chunks := splitAll(t, s)
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("chunks do not reassemble to the input")
+	}
+
+	if m.chunks != len(chunks) || m.chunkSize != len(data) {
+		t.Fatalf("reported %d chunks of %d bytes, expected %d of %d", m.chunks, m.chunkSize, len(chunks), len(data))
+	}
+	if m.readBytes != len(data) || m.reads == 0 {
+		t.Fatalf("reported %d bytes read, expected %d", m.readBytes, len(data))
+	}
+	if m.readTime < time.Duration(m.reads)*time.Millisecond {
+		t.Fatalf("reported %s spent reading in %d reads", m.readTime, m.reads)
+	}
+	// One more call returns io.EOF.
+	if m.splits != len(chunks)+1 {
+		t.Fatalf("reported %d calls to NextBytes, expected %d", m.splits, len(chunks)+1)
+	}
+}
+
+func TestInstrumentedReaderAt(t *testing.T) {
+	m := &testMetrics{}
+	NewInstrumentedSplitter(bytes.NewReader(nil), func(r io.Reader) Splitter {
+		if _, ok := r.(io.ReaderAt); !ok {
+			t.Fatal("the reader does not implement io.ReaderAt")
+		}
+		if _, ok := r.(io.Seeker); !ok {
+			t.Fatal("the reader does not implement io.Seeker")
+		}
+		return DefaultSplitter(r)
+	}, m)
+}
+
+func TestPoolMetrics(t *testing.T) {
+	m := &testMetrics{}
+	SetPoolMetrics(m)
+	defer SetPoolMetrics(nil)
+
+	// Every splitter takes a buffer for its full chunk, and one for the
+	// short chunk, which it copies and puts back.
+	data := randBuf(t, 1500)
+	for i := 0; i < 10; i++ {
+		s := NewSizeSplitter(bytes.NewReader(data), 1024)
+		for {
+			b, err := s.NextBytes()
+			if err != nil {
+				break
+			}
+			// The chunks go to go-buffer-pool, not to the splitters.
+			pool.Put(b)
+		}
+	}
+
+	if m.hits+m.misses != 20 {
+		t.Fatalf("expected 20 buffers, got %d hits and %d misses", m.hits, m.misses)
+	}
+	if m.misses < 10 || m.hits == 0 {
+		t.Fatalf("expected both hits and misses, got %d hits and %d misses", m.hits, m.misses)
+	}
+}
+
+func TestPoolMetricsNoFalseHits(t *testing.T) {
+	m := &testMetrics{}
+	SetPoolMetrics(m)
+	defer SetPoolMetrics(nil)
+
+	// Buffers that are dropped instead of put back are never reused, even
+	// when the collector hands their memory out again.
+	const size = 3<<20 + 1
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 20; i++ {
+			getBuf(size)
+		}
+		runtime.GC()
+		runtime.GC()
+	}
+
+	if m.hits != 0 {
+		t.Fatalf("expected no hits, got %d hits and %d misses", m.hits, m.misses)
+	}
+}
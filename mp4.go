@@ -0,0 +1,143 @@
+package chunk
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// MP4Splitter implements the Splitter interface for ISO-BMFF files (MP4,
+// fragmented MP4, CMAF). It parses the top-level boxes and starts a new run
+// of chunks at every box other than mdat, which stays with the box before
+// it. Each moof is therefore aligned to a chunk boundary together with the
+// media data of its fragment, so that range requests for a fragment map to
+// whole blocks. Runs are split by an inner splitter, which subdivides large
+// mdat boxes.
+//
+// Data that cannot be parsed as a box, and everything after it, is handed
+// to the inner splitter unchanged.
+type MP4Splitter struct {
+	r     io.Reader
+	trap  *readTrap
+	br    *bufio.Reader
+	inner SplitterGen
+
+	cur         Splitter
+	passthrough bool
+
+	err error
+}
+
+// NewMP4Splitter returns a MP4Splitter which splits runs of boxes with the
+// given inner splitter.
+func NewMP4Splitter(r io.Reader, inner SplitterGen) *MP4Splitter {
+	trap := newReadTrap(r)
+	return &MP4Splitter{
+		r:     r,
+		trap:  trap,
+		br:    bufio.NewReader(trap),
+		inner: inner,
+	}
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (ms *MP4Splitter) Reader() io.Reader {
+	return ms.r
+}
+
+// NextBytes produces a new chunk.
+func (ms *MP4Splitter) NextBytes() ([]byte, error) {
+	return ms.trap.result(ms.nextBytes())
+}
+
+func (ms *MP4Splitter) nextBytes() ([]byte, error) {
+	if ms.err != nil {
+		return nil, ms.err
+	}
+
+	for {
+		if ms.cur != nil {
+			b, err := ms.cur.NextBytes()
+			if err == nil {
+				return b, nil
+			} else if err != io.EOF || ms.passthrough {
+				ms.err = err
+				return nil, err
+			}
+			ms.cur = nil
+		}
+
+		if _, err := ms.br.Peek(1); err != nil {
+			ms.err = err
+			return nil, err
+		}
+
+		size, _, ok := peekMP4Box(ms.br)
+		if !ok {
+			ms.passthrough = true
+			ms.cur = ms.inner(ms.br)
+			continue
+		}
+		ms.cur = ms.inner(&mp4Run{br: ms.br, remaining: size})
+	}
+}
+
+// peekMP4Box parses the header of the next box without consuming it. A
+// size of -1 means that the box extends to the end of the file.
+func peekMP4Box(br *bufio.Reader) (size int64, typ string, ok bool) {
+	hdr, _ := br.Peek(8)
+	if len(hdr) < 8 {
+		return 0, "", false
+	}
+	for _, c := range hdr[4:8] {
+		if c < 0x20 || c > 0x7e {
+			return 0, "", false
+		}
+	}
+	typ = string(hdr[4:8])
+
+	switch size = int64(binary.BigEndian.Uint32(hdr)); size {
+	case 0:
+		return -1, typ, true
+	case 1:
+		hdr, _ = br.Peek(16)
+		if len(hdr) < 16 {
+			return 0, "", false
+		}
+		large := binary.BigEndian.Uint64(hdr[8:])
+		if large < 16 || large > 1<<62 {
+			return 0, "", false
+		}
+		return int64(large), typ, true
+	default:
+		if size < 8 {
+			return 0, "", false
+		}
+		return size, typ, true
+	}
+}
+
+// mp4Run reads a box and any mdat boxes directly following it.
+type mp4Run struct {
+	br        *bufio.Reader
+	remaining int64
+}
+
+func (mr *mp4Run) Read(p []byte) (int, error) {
+	if mr.remaining == 0 {
+		size, typ, ok := peekMP4Box(mr.br)
+		if !ok || typ != "mdat" {
+			return 0, io.EOF
+		}
+		mr.remaining = size
+	}
+
+	if mr.remaining > 0 && int64(len(p)) > mr.remaining {
+		p = p[:mr.remaining]
+	}
+	n, err := mr.br.Read(p)
+	if mr.remaining > 0 {
+		mr.remaining -= int64(n)
+	}
+	return n, err
+}
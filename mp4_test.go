@@ -0,0 +1,74 @@
+package chunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func mp4Box(typ string, payload []byte) []byte {
+	box := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(box, uint32(8+len(payload)))
+	copy(box[4:], typ)
+	return append(box, payload...)
+}
+
+func mp4LargeBox(typ string, payload []byte) []byte {
+	box := make([]byte, 16, 16+len(payload))
+	binary.BigEndian.PutUint32(box, 1)
+	copy(box[4:], typ)
+	binary.BigEndian.PutUint64(box[8:], uint64(16+len(payload)))
+	return append(box, payload...)
+}
+
+func TestMP4Splitter(t *testing.T) {
+	var data []byte
+	data = append(data, mp4Box("ftyp", []byte("iso6cmfc"))...)
+	data = append(data, mp4Box("moov", randBuf(t, 3000))...)
+
+	var fragments []int
+	for i := 0; i < 5; i++ {
+		fragments = append(fragments, len(data))
+		data = append(data, mp4Box("moof", randBuf(t, 500))...)
+		if i == 3 {
+			data = append(data, mp4LargeBox("mdat", randBuf(t, 700<<10))...)
+		} else {
+			data = append(data, mp4Box("mdat", randBuf(t, 100<<10+i))...)
+		}
+	}
+	fragments = append(fragments, len(data))
+	data = append(data, mp4Box("mfra", randBuf(t, 100))...)
+
+	chunks := splitAll(t, NewMP4Splitter(bytes.NewReader(data), SizeSplitterGen(256<<10)))
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+
+	starts := chunkStarts(chunks)
+	for i, off := range fragments {
+		if !starts[off] {
+			t.Fatalf("fragment %d at %d does not start a chunk", i, off)
+		}
+	}
+	// Small fragments are kept in a single chunk.
+	if len(chunks) != 2+4+3+1 {
+		t.Fatalf("unexpected number of chunks: %v", chunkSizes(chunks))
+	}
+}
+
+func TestMP4SplitterInvalid(t *testing.T) {
+	good := append(mp4Box("ftyp", []byte("isom")), mp4Box("mdat", randBuf(t, 5000))...)
+
+	for name, data := range map[string][]byte{
+		"random":    randBuf(t, 1<<20),
+		"truncated": good[:len(good)-100],
+		"trailing":  append(append([]byte{}, good...), 1, 2, 3),
+		"toeof":     append(append([]byte{}, good...), 0, 0, 0, 0, 'm', 'd', 'a', 't', 1, 2, 3),
+		"badsize":   append(append([]byte{}, good...), 0, 0, 0, 4, 'f', 'r', 'e', 'e'),
+	} {
+		chunks := splitAll(t, NewMP4Splitter(bytes.NewReader(data), SizeSplitterGen(4096)))
+		if !bytes.Equal(bytes.Join(chunks, nil), data) {
+			t.Fatalf("%s: data was chunked incorrectly", name)
+		}
+	}
+}
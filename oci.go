@@ -0,0 +1,196 @@
+package chunk
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+var (
+	// ErrLayerIncomplete is returned by OCILayerSplitter.Verify when the
+	// layer has not been read to the end yet.
+	ErrLayerIncomplete = errors.New("layer has not been read to the end")
+	// ErrLayerDigest is returned by OCILayerSplitter.Verify when the layer
+	// does not match the expected digest.
+	ErrLayerDigest = errors.New("layer digest mismatch")
+)
+
+// DecompressError is returned by OCILayerSplitter when a compressed layer
+// cannot be decompressed, such as on a bad gzip header or checksum. Unlike
+// a ReadError, it does not come from the reader, and resuming the input
+// will not get past it.
+type DecompressError struct {
+	Err error
+}
+
+func (e *DecompressError) Error() string {
+	return "decompress layer: " + e.Err.Error()
+}
+
+func (e *DecompressError) Unwrap() error {
+	return e.Err
+}
+
+// OCILayerSplitter splits OCI and Docker image layers. Gzip compressed
+// layers are decompressed and the resulting tar stream is split with a
+// TarSplitter, so that layers differing in a few files still share most of
+// their chunks.
+//
+// Unlike other splitters, its chunks are those of the uncompressed tar
+// stream and not of its input, which is why it is not available from
+// FromString. Neither the gzip header nor the compression parameters are
+// kept, so the compressed blob cannot be regenerated from the chunks.
+// Instead, the digest of the input is computed while it is read, so that
+// Verify can check it against the image manifest before the chunks are
+// stored, and DiffID gives the digest of the chunks, to check against the
+// image configuration and to serve them as an uncompressed layer.
+type OCILayerSplitter struct {
+	r          io.Reader
+	trap       *readTrap
+	br         *bufio.Reader
+	compressed bool
+
+	digest *hashingReader
+	diffID *hashingReader
+	tar    *TarSplitter
+
+	done bool
+	err  error
+}
+
+// NewOCILayerSplitter returns an OCILayerSplitter reading a compressed or
+// uncompressed layer from r.
+func NewOCILayerSplitter(r io.Reader) (*OCILayerSplitter, error) {
+	trap := newReadTrap(r)
+	ls := &OCILayerSplitter{
+		r:      r,
+		trap:   trap,
+		digest: &hashingReader{r: trap, h: sha256.New()},
+	}
+	ls.br = bufio.NewReader(ls.digest)
+
+	var tr io.Reader = ls.br
+	if magic, _ := ls.br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(ls.br)
+		if trap.err != nil {
+			return nil, trap.err
+		} else if err != nil {
+			return nil, &DecompressError{Err: err}
+		}
+		ls.compressed = true
+		tr = decompressReader{zr}
+	}
+	ls.diffID = &hashingReader{r: tr, h: sha256.New()}
+	ls.tar = NewTarSplitter(ls.diffID, DefaultSplitter)
+
+	return ls, nil
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (ls *OCILayerSplitter) Reader() io.Reader {
+	return ls.r
+}
+
+// NextBytes produces a new chunk of the uncompressed layer.
+func (ls *OCILayerSplitter) NextBytes() ([]byte, error) {
+	return ls.trap.result(ls.nextBytes())
+}
+
+func (ls *OCILayerSplitter) nextBytes() ([]byte, error) {
+	if ls.err != nil {
+		return nil, ls.err
+	}
+
+	b, err := ls.tar.NextBytes()
+	var de *DecompressError
+	if errors.As(err, &de) {
+		// The tar splitter reports it as a ReadError at an offset into the
+		// uncompressed stream.
+		err = de
+	}
+	if err == io.EOF {
+		// Account for anything after the end of the compressed stream.
+		if _, err := io.Copy(io.Discard, ls.br); err != nil {
+			ls.err = err
+			return nil, err
+		}
+		// A read error ends the tar stream early too.
+		ls.done = ls.trap.err == nil
+	}
+	if err != nil {
+		ls.err = err
+	}
+	return b, err
+}
+
+// Compressed reports whether the layer is gzip compressed.
+func (ls *OCILayerSplitter) Compressed() bool {
+	return ls.compressed
+}
+
+// Digest returns the digest of the layer as it was read, in the
+// "sha256:<hex>" form used by image manifests. It is only complete once
+// NextBytes has returned io.EOF.
+func (ls *OCILayerSplitter) Digest() string {
+	return ls.digest.digest()
+}
+
+// DiffID returns the digest of the uncompressed layer. It is only complete
+// once NextBytes has returned io.EOF.
+func (ls *OCILayerSplitter) DiffID() string {
+	return ls.diffID.digest()
+}
+
+// Size returns the number of bytes read from the original stream.
+func (ls *OCILayerSplitter) Size() int64 {
+	return ls.digest.n
+}
+
+// Verify checks that the layer matches the given digest. It must be called
+// after NextBytes has returned io.EOF.
+func (ls *OCILayerSplitter) Verify(digest string) error {
+	if !ls.done {
+		return ErrLayerIncomplete
+	}
+	if got := ls.Digest(); got != digest {
+		return fmt.Errorf("%w: expected %s, got %s", ErrLayerDigest, digest, got)
+	}
+	return nil
+}
+
+// decompressReader turns the errors of a decompressor into a
+// DecompressError.
+type decompressReader struct {
+	r io.Reader
+}
+
+func (dr decompressReader) Read(p []byte) (int, error) {
+	n, err := dr.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = &DecompressError{Err: err}
+	}
+	return n, err
+}
+
+// hashingReader hashes everything read through it.
+type hashingReader struct {
+	r io.Reader
+	h hash.Hash
+	n int64
+}
+
+func (hr *hashingReader) Read(p []byte) (int, error) {
+	n, err := hr.r.Read(p)
+	hr.h.Write(p[:n])
+	hr.n += int64(n)
+	return n, err
+}
+
+func (hr *hashingReader) digest() string {
+	return "sha256:" + hex.EncodeToString(hr.h.Sum(nil))
+}
@@ -0,0 +1,135 @@
+package chunk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+)
+
+func sha256Digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func gzipLayer(t *testing.T, tarball []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(tarball); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOCILayerSplitter(t *testing.T) {
+	shared := randBuf(t, 500<<10)
+	tarA := makeTar(t, []tarEntry{{"etc/a", []byte("one")}, {"usr/lib/big", shared}})
+	tarB := makeTar(t, []tarEntry{{"etc/a", []byte("two")}, {"usr/lib/big", shared}})
+	layerA, layerB := gzipLayer(t, tarA), gzipLayer(t, tarB)
+
+	ls, err := NewOCILayerSplitter(bytes.NewReader(layerA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.Verify(sha256Digest(layerA)); err != ErrLayerIncomplete {
+		t.Fatalf("Expected 'ErrLayerIncomplete', got: %#v", err)
+	}
+
+	chunksA := splitAll(t, ls)
+	if !bytes.Equal(bytes.Join(chunksA, nil), tarA) {
+		t.Fatal("layer was chunked incorrectly")
+	}
+	if !ls.Compressed() || ls.Size() != int64(len(layerA)) {
+		t.Fatalf("unexpected layer properties: compressed %v, size %d", ls.Compressed(), ls.Size())
+	}
+	if err := ls.Verify(sha256Digest(layerA)); err != nil {
+		t.Fatal(err)
+	}
+	if ls.DiffID() != sha256Digest(tarA) {
+		t.Fatal("unexpected diff id")
+	}
+	if err := ls.Verify(sha256Digest(layerB)); !errors.Is(err, ErrLayerDigest) {
+		t.Fatalf("Expected 'ErrLayerDigest', got: %#v", err)
+	}
+
+	ls, err = NewOCILayerSplitter(bytes.NewReader(layerB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, chunk := range chunksA {
+		seen[string(chunk)] = true
+	}
+	var common int
+	for _, chunk := range splitAll(t, ls) {
+		if seen[string(chunk)] {
+			common += len(chunk)
+		}
+	}
+	if common < len(shared) {
+		t.Fatalf("expected the shared file to dedup, only %d bytes in common", common)
+	}
+
+	// Its chunks do not reassemble to its input.
+	if _, err := FromString(bytes.NewReader(layerA), "oci-layer"); err == nil {
+		t.Fatal("expected FromString to reject oci-layer")
+	}
+}
+
+func TestOCILayerSplitterUncompressed(t *testing.T) {
+	tarball := makeTar(t, []tarEntry{{"file", randBuf(t, 1000)}})
+
+	ls, err := NewOCILayerSplitter(bytes.NewReader(tarball))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bytes.Join(splitAll(t, ls), nil), tarball) {
+		t.Fatal("layer was chunked incorrectly")
+	}
+	if ls.Compressed() {
+		t.Fatal("uncompressed layer reported as compressed")
+	}
+	if err := ls.Verify(sha256Digest(tarball)); err != nil {
+		t.Fatal(err)
+	}
+	if ls.DiffID() != ls.Digest() {
+		t.Fatal("diff id of an uncompressed layer must match its digest")
+	}
+}
+
+func TestOCILayerSplitterCorrupt(t *testing.T) {
+	layer := gzipLayer(t, makeTar(t, []tarEntry{{"file", randBuf(t, 100000)}}))
+	layer[len(layer)-5] ^= 0xff
+
+	ls, err := NewOCILayerSplitter(bytes.NewReader(layer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		_, err := ls.NextBytes()
+		if err == io.EOF {
+			t.Fatal("expected a checksum error")
+		} else if err != nil {
+			var de *DecompressError
+			if !errors.As(err, &de) || !errors.Is(err, gzip.ErrChecksum) {
+				t.Fatalf("expected a DecompressError, got %v", err)
+			}
+			var re *ReadError
+			if errors.As(err, &re) {
+				t.Fatalf("a corrupt layer was reported as a ReadError: %v", err)
+			}
+			break
+		}
+	}
+
+	layer = append([]byte{0x1f, 0x8b, 0x08, 0xff}, layer[4:]...)
+	if _, err := NewOCILayerSplitter(bytes.NewReader(layer)); !errors.As(err, new(*DecompressError)) {
+		t.Fatalf("expected a DecompressError for a bad header, got %v", err)
+	}
+}
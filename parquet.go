@@ -0,0 +1,308 @@
+package chunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+var parquetMagic = []byte("PAR1")
+
+// ErrNotParquet is returned by NewParquetSplitter when the input is not a
+// Parquet file or its metadata cannot be parsed.
+var ErrNotParquet = errors.New("not a parquet file")
+
+// ParquetSplitter implements the Splitter interface for Parquet files. It
+// reads the file metadata from the footer and cuts at the start and end of
+// every column chunk, so that range reads of a column in a row group map to
+// whole blocks. Column chunks, as well as the header and footer, are split
+// by an inner splitter.
+type ParquetSplitter struct {
+	sectionSplitter
+}
+
+// NewParquetSplitter returns a ParquetSplitter for the file of the given
+// size in r, which splits column chunks with the given inner splitter.
+func NewParquetSplitter(r io.ReaderAt, size int64, inner SplitterGen) (*ParquetSplitter, error) {
+	if size < int64(2*len(parquetMagic)+4) {
+		return nil, ErrNotParquet
+	}
+
+	tail := make([]byte, 8)
+	if _, err := r.ReadAt(tail, size-8); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(tail[4:], parquetMagic) {
+		return nil, ErrNotParquet
+	}
+	footerLen := int64(binary.LittleEndian.Uint32(tail))
+	footerOff := size - 8 - footerLen
+	if footerOff < int64(len(parquetMagic)) {
+		return nil, ErrNotParquet
+	}
+
+	footer := make([]byte, footerLen)
+	if _, err := r.ReadAt(footer, footerOff); err != nil {
+		return nil, err
+	}
+
+	cuts, err := parquetColumnChunks(footer)
+	if err != nil {
+		return nil, err
+	}
+	cuts = append(cuts, int64(len(parquetMagic)), footerOff)
+
+	return &ParquetSplitter{newSectionSplitter(r, size, inner, cuts)}, nil
+}
+
+// parquetColumnChunks returns the start and end offsets of every column
+// chunk listed in the Thrift encoded FileMetaData.
+func parquetColumnChunks(footer []byte) ([]int64, error) {
+	tr := &thriftReader{b: footer}
+	var cuts []int64
+
+	// FileMetaData.row_groups is field 4.
+	tr.readStruct(func(id int16, typ byte) bool {
+		if id != 4 || typ != thriftList {
+			return false
+		}
+		tr.readList(func(typ byte) bool {
+			if typ != thriftStruct {
+				return false
+			}
+			// RowGroup.columns is field 1.
+			tr.readStruct(func(id int16, typ byte) bool {
+				if id != 1 || typ != thriftList {
+					return false
+				}
+				tr.readList(func(typ byte) bool {
+					if typ != thriftStruct {
+						return false
+					}
+					// ColumnChunk.meta_data is field 3.
+					tr.readStruct(func(id int16, typ byte) bool {
+						if id != 3 || typ != thriftStruct {
+							return false
+						}
+						cuts = append(cuts, tr.readColumnMetaData()...)
+						return true
+					})
+					return true
+				})
+				return true
+			})
+			return true
+		})
+		return true
+	})
+
+	if tr.err != nil {
+		return nil, ErrNotParquet
+	}
+	return cuts, nil
+}
+
+// readColumnMetaData returns the start and end offsets of a column chunk.
+func (tr *thriftReader) readColumnMetaData() []int64 {
+	var size, dataOff, dictOff int64
+	tr.readStruct(func(id int16, typ byte) bool {
+		if typ != thriftI64 {
+			return false
+		}
+		switch id {
+		case 7: // total_compressed_size
+			size = tr.readI64()
+		case 9: // data_page_offset
+			dataOff = tr.readI64()
+		case 11: // dictionary_page_offset
+			dictOff = tr.readI64()
+		default:
+			return false
+		}
+		return true
+	})
+
+	start := dataOff
+	if dictOff > 0 && dictOff < start {
+		start = dictOff
+	}
+	if start <= 0 || size <= 0 || start > math.MaxInt64-size {
+		return nil
+	}
+	return []int64{start, start + size}
+}
+
+// Thrift compact protocol types.
+const (
+	thriftBoolTrue  = 1
+	thriftBoolFalse = 2
+	thriftByte      = 3
+	thriftI16       = 4
+	thriftI32       = 5
+	thriftI64       = 6
+	thriftDouble    = 7
+	thriftBinary    = 8
+	thriftList      = 9
+	thriftSet       = 10
+	thriftMap       = 11
+	thriftStruct    = 12
+
+	thriftMaxDepth = 64
+)
+
+var errThrift = errors.New("invalid thrift data")
+
+// thriftReader decodes just enough of the Thrift compact protocol to walk
+// Parquet metadata. Errors are sticky and stop all further reading.
+type thriftReader struct {
+	b     []byte
+	depth int
+	err   error
+}
+
+func (tr *thriftReader) fail() {
+	if tr.err == nil {
+		tr.err = errThrift
+	}
+	tr.b = nil
+}
+
+func (tr *thriftReader) readByte() byte {
+	if len(tr.b) == 0 {
+		tr.fail()
+		return 0
+	}
+	b := tr.b[0]
+	tr.b = tr.b[1:]
+	return b
+}
+
+func (tr *thriftReader) readUvarint() uint64 {
+	v, n := binary.Uvarint(tr.b)
+	if n <= 0 {
+		tr.fail()
+		return 0
+	}
+	tr.b = tr.b[n:]
+	return v
+}
+
+func (tr *thriftReader) readI64() int64 {
+	v := tr.readUvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// readStruct calls field for every field of a struct. field returns false
+// if it did not consume the value, which is then skipped.
+func (tr *thriftReader) readStruct(field func(id int16, typ byte) bool) {
+	tr.depth++
+	defer func() { tr.depth-- }()
+	if tr.depth > thriftMaxDepth {
+		tr.fail()
+		return
+	}
+
+	var id int16
+	for tr.err == nil {
+		b := tr.readByte()
+		if b == 0 {
+			return
+		}
+		typ := b & 0x0f
+		if delta := int16(b >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(tr.readI64())
+		}
+		if !field(id, typ) {
+			tr.skip(typ)
+		}
+	}
+}
+
+// readList calls elem for every element of a list or set. elem returns
+// false if it did not consume the value, which is then skipped.
+func (tr *thriftReader) readList(elem func(typ byte) bool) {
+	b := tr.readByte()
+	size := uint64(b >> 4)
+	typ := b & 0x0f
+	if size == 15 {
+		size = tr.readUvarint()
+	}
+	if size > uint64(len(tr.b)) {
+		// Every element takes at least one byte.
+		tr.fail()
+		return
+	}
+	for i := uint64(0); i < size && tr.err == nil; i++ {
+		if !elem(typ) {
+			tr.skip(typ)
+		}
+	}
+}
+
+func (tr *thriftReader) skip(typ byte) {
+	tr.depth++
+	defer func() { tr.depth-- }()
+	if tr.depth > thriftMaxDepth {
+		tr.fail()
+		return
+	}
+
+	switch typ {
+	case thriftBoolTrue, thriftBoolFalse:
+		// The value is part of the field header.
+	case thriftByte:
+		tr.readByte()
+	case thriftI16, thriftI32, thriftI64:
+		tr.readUvarint()
+	case thriftDouble:
+		if len(tr.b) < 8 {
+			tr.fail()
+			return
+		}
+		tr.b = tr.b[8:]
+	case thriftBinary:
+		n := tr.readUvarint()
+		if n > uint64(len(tr.b)) {
+			tr.fail()
+			return
+		}
+		tr.b = tr.b[n:]
+	case thriftList, thriftSet:
+		tr.readList(func(typ byte) bool {
+			tr.skipElem(typ)
+			return true
+		})
+	case thriftMap:
+		size := tr.readUvarint()
+		if size == 0 {
+			return
+		}
+		kv := tr.readByte()
+		if size > uint64(len(tr.b)) {
+			tr.fail()
+			return
+		}
+		for i := uint64(0); i < size && tr.err == nil; i++ {
+			tr.skipElem(kv >> 4)
+			tr.skipElem(kv & 0x0f)
+		}
+	case thriftStruct:
+		tr.readStruct(func(int16, byte) bool { return false })
+	default:
+		tr.fail()
+	}
+}
+
+// skipElem skips a list, set or map element. Unlike struct fields, boolean
+// elements take a byte of their own.
+func (tr *thriftReader) skipElem(typ byte) {
+	if typ == thriftBoolTrue || typ == thriftBoolFalse {
+		tr.readByte()
+		return
+	}
+	tr.skip(typ)
+}
@@ -0,0 +1,149 @@
+package chunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// thriftWriter encodes the subset of the Thrift compact protocol needed to
+// build Parquet footers in tests.
+type thriftWriter struct {
+	buf  []byte
+	last []int16
+}
+
+func (tw *thriftWriter) field(id int16, typ byte) {
+	prev := tw.last[len(tw.last)-1]
+	if d := id - prev; d > 0 && d <= 15 {
+		tw.buf = append(tw.buf, byte(d)<<4|typ)
+	} else {
+		tw.buf = append(tw.buf, typ)
+		tw.buf = binary.AppendUvarint(tw.buf, uint64(id)<<1^uint64(id>>15))
+	}
+	tw.last[len(tw.last)-1] = id
+}
+
+func (tw *thriftWriter) i64(id int16, v int64) {
+	tw.field(id, thriftI64)
+	tw.buf = binary.AppendUvarint(tw.buf, uint64(v<<1^v>>63))
+}
+
+func (tw *thriftWriter) binary(id int16, b []byte) {
+	tw.field(id, thriftBinary)
+	tw.buf = binary.AppendUvarint(tw.buf, uint64(len(b)))
+	tw.buf = append(tw.buf, b...)
+}
+
+func (tw *thriftWriter) begin(id int16) {
+	tw.field(id, thriftStruct)
+	tw.last = append(tw.last, 0)
+}
+
+func (tw *thriftWriter) beginElem() {
+	tw.last = append(tw.last, 0)
+}
+
+func (tw *thriftWriter) end() {
+	tw.buf = append(tw.buf, 0)
+	tw.last = tw.last[:len(tw.last)-1]
+}
+
+func (tw *thriftWriter) list(id int16, typ byte, n int) {
+	tw.field(id, thriftList)
+	if n < 15 {
+		tw.buf = append(tw.buf, byte(n)<<4|typ)
+	} else {
+		tw.buf = append(tw.buf, 0xf0|typ)
+		tw.buf = binary.AppendUvarint(tw.buf, uint64(n))
+	}
+}
+
+// makeParquet builds a file with random column chunks of the given sizes
+// per row group, and returns it along with the column chunk offsets.
+func makeParquet(t testing.TB, rowGroups [][]int) ([]byte, []int) {
+	data := append([]byte{}, parquetMagic...)
+	var offsets []int
+
+	tw := &thriftWriter{last: []int16{0}}
+	tw.i64(3, 1000) // num_rows
+	tw.list(4, thriftStruct, len(rowGroups))
+	for _, columns := range rowGroups {
+		tw.beginElem()
+		tw.list(1, thriftStruct, len(columns))
+		for i, size := range columns {
+			off := len(data)
+			offsets = append(offsets, off)
+			data = append(data, randBuf(t, size)...)
+
+			tw.beginElem()
+			tw.binary(1, []byte("path"))
+			tw.i64(2, int64(off))
+			tw.begin(3)
+			tw.list(2, thriftI32, 1)
+			tw.buf = append(tw.buf, 0)
+			tw.i64(5, 100)
+			tw.i64(7, int64(size))
+			if i == 0 {
+				// Dictionary page before the data pages.
+				tw.i64(9, int64(off+10))
+				tw.i64(11, int64(off))
+			} else {
+				tw.i64(9, int64(off))
+			}
+			tw.begin(12) // statistics
+			tw.binary(1, []byte{1, 2, 3})
+			tw.end()
+			tw.end()
+			tw.end()
+		}
+		tw.i64(2, 12345)
+		tw.end()
+	}
+	tw.binary(6, []byte("created by test"))
+	tw.buf = append(tw.buf, 0)
+
+	data = append(data, tw.buf...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(tw.buf)))
+	data = append(data, parquetMagic...)
+	return data, offsets
+}
+
+func TestParquetSplitter(t *testing.T) {
+	data, offsets := makeParquet(t, [][]int{{1000, 300000, 20}, {5000, 70000, 1}})
+
+	s, err := FromString(bytes.NewReader(data), "parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := splitAll(t, s)
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+
+	starts := chunkStarts(chunks)
+	for i, off := range offsets {
+		if !starts[off] {
+			t.Fatalf("column chunk %d at %d does not start a chunk", i, off)
+		}
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if !starts[len(data)-8-footerLen] {
+		t.Fatal("footer does not start a chunk")
+	}
+}
+
+func TestParquetSplitterInvalid(t *testing.T) {
+	data, _ := makeParquet(t, [][]int{{100}})
+
+	for name, b := range map[string][]byte{
+		"random":    randBuf(t, 1000),
+		"short":     []byte("PAR1PAR1"),
+		"badlength": append(append([]byte{}, data[:len(data)-8]...), 0xff, 0xff, 0xff, 0x00, 'P', 'A', 'R', '1'),
+		"truncated": append(append([]byte("PAR1"), data[len(data)-12:len(data)-8]...), 8, 0, 0, 0, 'P', 'A', 'R', '1'),
+	} {
+		if _, err := NewParquetSplitter(bytes.NewReader(b), int64(len(b)), DefaultSplitter); err != ErrNotParquet {
+			t.Fatalf("%s: Expected 'ErrNotParquet', got: %#v", name, err)
+		}
+	}
+}
@@ -29,11 +29,29 @@ var (
 	ErrSize = errors.New("chunker size must be greater than 0")
 	// Deprecated: use github.com/ipfs/boxo/chunker.ErrSizeMax
 	ErrSizeMax = fmt.Errorf("chunker parameters may not exceed the maximum chunk size of %d", ChunkSizeLimit)
+	// ErrNotSeekable is returned by FromString for chunkers which need random
+	// access when the reader does not provide it.
+	ErrNotSeekable = errors.New("chunker requires an io.ReaderAt and io.Seeker")
+	// ErrCasyncMin is returned when the casync min chunk size is smaller than its hash window.
+	ErrCasyncMin = fmt.Errorf("casync min must be at least %d", casyncWindow)
+	// ErrRollsumBits is returned when the rollsum average size is out of range.
+	ErrRollsumBits = fmt.Errorf("rollsum bits must be between %d and %d", rollsumMinBits, rollsumMaxBits)
+	// ErrRabinAvg is returned when the rabin average size is not between its min and max.
+	ErrRabinAvg = errors.New("rabin avg must be between min and max")
+	// ErrCasyncAvg is returned when the casync average size is not strictly between its min and max.
+	ErrCasyncAvg = errors.New("casync avg must be greater than min and smaller than max")
 )
 
 // FromString returns a Splitter depending on the given string:
 // it supports "default" (""), "size-{size}", "rabin", "rabin-{blocksize}",
-// "rabin-{min}-{avg}-{max}" and "buzhash".
+// "rabin-{min}-{avg}-{max}", "rabin-tttd", "buzhash", "buzhash-tttd",
+// "casync", "casync-{min}-{avg}-{max}", "rollsum", "rollsum-{bits}", "tar",
+// "gzip", "zip", "mp4", "sqlite", "parquet", "lines-{size}", "csv-{size}",
+// "varint-{size}" and "sparse-{size}". Chunkers which need random access to the input, like
+// "zip" and "parquet", require r to implement io.ReaderAt and io.Seeker.
+//
+// All of them produce chunks which reassemble to the input. Image layers,
+// which are split once decompressed, are handled by NewOCILayerSplitter.
 //
 // Deprecated: use github.com/ipfs/boxo/chunker.FromString
 func FromString(r io.Reader, chunker string) (Splitter, error) {
@@ -42,37 +60,148 @@ func FromString(r io.Reader, chunker string) (Splitter, error) {
 		return DefaultSplitter(r), nil
 
 	case strings.HasPrefix(chunker, "size-"):
-		sizeStr := strings.Split(chunker, "-")[1]
-		size, err := strconv.Atoi(sizeStr)
+		size, err := parseSize(strings.TrimPrefix(chunker, "size-"))
 		if err != nil {
 			return nil, err
-		} else if size <= 0 {
-			return nil, ErrSize
-		} else if size > ChunkSizeLimit {
-			return nil, ErrSizeMax
 		}
 		return NewSizeSplitter(r, int64(size)), nil
 
+	case chunker == "rabin-tttd":
+		avg := uint64(DefaultBlockSize)
+		return NewRabinTTTD(r, avg/3, avg, avg+avg/2)
+
+	case strings.HasPrefix(chunker, "lines-"):
+		size, err := parseSize(strings.TrimPrefix(chunker, "lines-"))
+		if err != nil {
+			return nil, err
+		}
+		return NewRecordSplitter(r, int64(size)), nil
+
+	case strings.HasPrefix(chunker, "csv-"):
+		size, err := parseSize(strings.TrimPrefix(chunker, "csv-"))
+		if err != nil {
+			return nil, err
+		}
+		return NewCSVSplitter(r, int64(size)), nil
+
+	case strings.HasPrefix(chunker, "varint-"):
+		size, err := parseSize(strings.TrimPrefix(chunker, "varint-"))
+		if err != nil {
+			return nil, err
+		}
+		return NewVarintFramedSplitter(r, int64(size))
+
+	case strings.HasPrefix(chunker, "sparse-"):
+		size, err := parseSize(strings.TrimPrefix(chunker, "sparse-"))
+		if err != nil {
+			return nil, err
+		}
+		return NewSparseSplitter(r, int64(size), DefaultSplitter)
+
 	case strings.HasPrefix(chunker, "rabin"):
 		return parseRabinString(r, chunker)
 
 	case chunker == "buzhash":
 		return NewBuzhash(r), nil
 
+	case chunker == "buzhash-tttd":
+		return NewBuzhashTTTD(r), nil
+
+	case strings.HasPrefix(chunker, "casync"):
+		return parseCasyncString(r, chunker)
+
+	case chunker == "rollsum":
+		return NewRollsum(r, rollsumDefaultBits)
+
+	case chunker == "tar":
+		return NewTarSplitter(r, DefaultSplitter), nil
+
+	case chunker == "gzip":
+		return NewGzipSplitter(r, DefaultSplitter), nil
+
+	case chunker == "mp4":
+		return NewMP4Splitter(r, DefaultSplitter), nil
+
+	case chunker == "sqlite":
+		return NewAlignedSplitter(r, 0, 0, 0)
+
+	case chunker == "parquet":
+		ra, size, err := readerAt(r)
+		if err != nil {
+			return nil, err
+		}
+		return NewParquetSplitter(ra, size, DefaultSplitter)
+
+	case chunker == "zip":
+		ra, size, err := readerAt(r)
+		if err != nil {
+			return nil, err
+		}
+		return NewZipSplitter(ra, size, DefaultSplitter)
+
+	case strings.HasPrefix(chunker, "rollsum-"):
+		bits, err := strconv.Atoi(strings.TrimPrefix(chunker, "rollsum-"))
+		if err != nil {
+			return nil, err
+		} else if bits < 0 {
+			return nil, ErrRollsumBits
+		}
+		return NewRollsum(r, uint(bits))
+
 	default:
 		return nil, fmt.Errorf("unrecognized chunker option: %s", chunker)
 	}
 }
 
+// readerAt returns r as an io.ReaderAt along with the size of its content.
+func readerAt(r io.Reader) (io.ReaderAt, int64, error) {
+	ra, ok := r.(io.ReaderAt)
+	if !ok {
+		return nil, 0, ErrNotSeekable
+	}
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return nil, 0, ErrNotSeekable
+	}
+	size, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, err := s.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	return ra, size, nil
+}
+
+// parseSize parses a chunk size, which must be positive and may not exceed
+// ChunkSizeLimit.
+func parseSize(sizeStr string) (int, error) {
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil {
+		return 0, err
+	} else if size <= 0 {
+		return 0, ErrSize
+	} else if size > ChunkSizeLimit {
+		return 0, ErrSizeMax
+	}
+	return size, nil
+}
+
 func parseRabinString(r io.Reader, chunker string) (Splitter, error) {
 	parts := strings.Split(chunker, "-")
 	switch len(parts) {
 	case 1:
+		if parts[0] != "rabin" {
+			return nil, fmt.Errorf("unrecognized chunker option: %s", chunker)
+		}
 		return NewRabin(r, uint64(DefaultBlockSize)), nil
 	case 2:
 		size, err := strconv.Atoi(parts[1])
 		if err != nil {
 			return nil, err
+		} else if size/3 < 16 {
+			// NewRabin uses a third of the average as the min size.
+			return nil, ErrRabinMin
 		} else if int(float32(size)*1.5) > ChunkSizeLimit { // FIXME - this will be addressed in a subsequent PR
 			return nil, ErrSizeMax
 		}
@@ -121,3 +250,31 @@ func parseRabinString(r io.Reader, chunker string) (Splitter, error) {
 		return nil, errors.New("incorrect format (expected 'rabin' 'rabin-[avg]' or 'rabin-[min]-[avg]-[max]'")
 	}
 }
+
+func parseCasyncString(r io.Reader, chunker string) (Splitter, error) {
+	parts := strings.Split(chunker, "-")
+	switch len(parts) {
+	case 1:
+		if parts[0] != "casync" {
+			return nil, fmt.Errorf("unrecognized chunker option: %s", chunker)
+		}
+		return NewCasync(r), nil
+	case 4:
+		var sizes [3]int
+		for i, p := range parts[1:] {
+			size, err := strconv.Atoi(p)
+			if err != nil {
+				return nil, err
+			}
+			sizes[i] = size
+		}
+		for _, size := range sizes {
+			if size < 0 {
+				return nil, ErrSize
+			}
+		}
+		return NewCasyncMinMax(r, uint64(sizes[0]), uint64(sizes[1]), uint64(sizes[2]))
+	default:
+		return nil, errors.New("incorrect format (expected 'casync' or 'casync-[min]-[avg]-[max]')")
+	}
+}
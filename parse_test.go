@@ -53,6 +53,21 @@ func TestParseRabin(t *testing.T) {
 		t.Fatalf("Expected 'ErrSizeMax', got: %#v", err)
 	}
 
+	_, err = FromString(r, "rabin-47")
+	if err != ErrRabinMin {
+		t.Fatalf("Expected an 'ErrRabinMin' error, got: %#v", err)
+	}
+
+	_, err = FromString(r, "rabin-0")
+	if err != ErrRabinMin {
+		t.Fatalf("Expected an 'ErrRabinMin' error, got: %#v", err)
+	}
+
+	_, err = FromString(r, "rabinfoo")
+	if err == nil {
+		t.Fatal("Expected an error for an unknown rabin variant")
+	}
+
 }
 
 func TestParseSize(t *testing.T) {
@@ -63,6 +78,16 @@ func TestParseSize(t *testing.T) {
 		t.Fatalf("Expected an 'ErrSize' error, got: %#v", err)
 	}
 
+	_, err = FromString(r, "size-1-2")
+	if err == nil {
+		t.Fatal("Expected an error for extra dashes")
+	}
+
+	_, err = FromString(r, "size-")
+	if err == nil {
+		t.Fatal("Expected an error for a missing size")
+	}
+
 	_, err = FromString(r, "size-32")
 	if err != nil {
 		t.Fatalf("Expected success, got: %#v", err)
@@ -78,3 +103,86 @@ func TestParseSize(t *testing.T) {
 		t.Fatalf("Expected 'ErrSizeMax', got: %#v", err)
 	}
 }
+
+func TestParseCasync(t *testing.T) {
+	r := bytes.NewReader(randBuf(t, 1000))
+
+	_, err := FromString(r, "casync")
+	if err != nil {
+		t.Fatalf("Expected success, got: %#v", err)
+	}
+
+	_, err = FromString(r, "casync-48-64-128")
+	if err != nil {
+		t.Fatalf("Expected success, got: %#v", err)
+	}
+
+	_, err = FromString(r, "casync-47-64-128")
+	if err != ErrCasyncMin {
+		t.Fatalf("Expected an 'ErrCasyncMin' error, got: %#v", err)
+	}
+
+	_, err = FromString(r, "casync-64-64-128")
+	if err != ErrCasyncAvg {
+		t.Fatalf("Expected an 'ErrCasyncAvg' error, got: %#v", err)
+	}
+
+	_, err = FromString(r, "casync-48-128-128")
+	if err != ErrCasyncAvg {
+		t.Fatalf("Expected an 'ErrCasyncAvg' error, got: %#v", err)
+	}
+
+	_, err = FromString(r, fmt.Sprintf("casync-48-64-%d", 1+ChunkSizeLimit))
+	if err != ErrSizeMax {
+		t.Fatalf("Expected 'ErrSizeMax', got: %#v", err)
+	}
+
+	_, err = FromString(r, "casyncfoo")
+	if err == nil {
+		t.Fatal("Expected an error for an unknown casync variant")
+	}
+}
+
+func TestParseRollsum(t *testing.T) {
+	r := bytes.NewReader(randBuf(t, 1000))
+
+	_, err := FromString(r, "rollsum")
+	if err != nil {
+		t.Fatalf("Expected success, got: %#v", err)
+	}
+
+	_, err = FromString(r, "rollsum-16")
+	if err != nil {
+		t.Fatalf("Expected success, got: %#v", err)
+	}
+
+	_, err = FromString(r, "rollsum-17")
+	if err != ErrRollsumBits {
+		t.Fatalf("Expected an 'ErrRollsumBits' error, got: %#v", err)
+	}
+
+	_, err = FromString(r, "rollsum-5")
+	if err != ErrRollsumBits {
+		t.Fatalf("Expected an 'ErrRollsumBits' error, got: %#v", err)
+	}
+}
+
+func TestParseTTTD(t *testing.T) {
+	r := bytes.NewReader(randBuf(t, 1000))
+
+	s, err := FromString(r, "rabin-tttd")
+	if err != nil {
+		t.Fatalf("Expected success, got: %#v", err)
+	}
+	if s.(*Rabin).tttd == nil {
+		t.Fatal("Expected a TTTD rabin splitter")
+	}
+
+	s, err = FromString(r, "buzhash-tttd")
+	if err != nil {
+		t.Fatalf("Expected success, got: %#v", err)
+	}
+	if !s.(*Buzhash).tttd {
+		t.Fatal("Expected a TTTD buzhash splitter")
+	}
+}
@@ -0,0 +1,54 @@
+package chunk
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+)
+
+// bufferPool recycles the buffers of the splitters of this package. It is
+// laid out like go-buffer-pool, with a sync.Pool for every power of two,
+// but tells whether a buffer was reused so that it can be reported to the
+// PoolMetrics.
+type bufferPool struct {
+	pools [32]sync.Pool
+	ptrs  sync.Pool
+}
+
+var bufPool bufferPool
+
+// get returns a buffer of the given length, and whether it was taken from
+// the pool rather than allocated.
+func (p *bufferPool) get(length int) ([]byte, bool) {
+	if length == 0 {
+		return nil, false
+	} else if length > math.MaxInt32 {
+		return make([]byte, length), false
+	}
+
+	idx := bits.Len32(uint32(length) - 1)
+	if v := p.pools[idx].Get(); v != nil {
+		bp := v.(*[]byte)
+		b := (*bp)[:length]
+		*bp = nil
+		p.ptrs.Put(bp)
+		return b, true
+	}
+	return make([]byte, 1<<idx)[:length], false
+}
+
+// put adds b to the pool, in the class of the largest power of two which
+// fits its capacity.
+func (p *bufferPool) put(b []byte) {
+	c := cap(b)
+	if c == 0 || c > math.MaxInt32 {
+		return
+	}
+
+	bp, _ := p.ptrs.Get().(*[]byte)
+	if bp == nil {
+		bp = new([]byte)
+	}
+	*bp = b[:0]
+	p.pools[bits.Len32(uint32(c))-1].Put(bp)
+}
@@ -0,0 +1,103 @@
+// Package prommetrics implements chunk.Metrics and chunk.PoolMetrics with
+// Prometheus collectors.
+package prommetrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics collects the measurements of instrumented splitters. It
+// implements chunk.Metrics and chunk.PoolMetrics.
+type Metrics struct {
+	readBytes    prometheus.Counter
+	readSeconds  prometheus.Counter
+	splitSeconds prometheus.Counter
+	chunks       prometheus.Counter
+	chunkSizes   prometheus.Histogram
+	poolGets     *prometheus.CounterVec
+}
+
+// New creates the collectors, with the given constant labels, and
+// registers them with reg, which is usually a registry local to the
+// caller rather than prometheus.DefaultRegisterer.
+func New(reg prometheus.Registerer, labels prometheus.Labels) (*Metrics, error) {
+	m := &Metrics{
+		readBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "chunker",
+			Name:        "read_bytes_total",
+			Help:        "Bytes read from the input by splitters.",
+			ConstLabels: labels,
+		}),
+		readSeconds: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "chunker",
+			Name:        "read_seconds_total",
+			Help:        "Time spent reading the input.",
+			ConstLabels: labels,
+		}),
+		splitSeconds: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "chunker",
+			Name:        "split_seconds_total",
+			Help:        "Time spent in splitters finding boundaries, not counting reads.",
+			ConstLabels: labels,
+		}),
+		chunks: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "chunker",
+			Name:        "chunks_total",
+			Help:        "Chunks produced by splitters.",
+			ConstLabels: labels,
+		}),
+		chunkSizes: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   "chunker",
+			Name:        "chunk_size_bytes",
+			Help:        "Size of the chunks produced by splitters.",
+			ConstLabels: labels,
+			// 1KiB to 1MiB.
+			Buckets: prometheus.ExponentialBuckets(1<<10, 2, 11),
+		}),
+		poolGets: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "chunker",
+			Name:        "pool_gets_total",
+			Help:        "Buffers taken from their pool by splitters, by whether they were reused.",
+			ConstLabels: labels,
+		}, []string{"result"}),
+	}
+
+	for _, c := range []prometheus.Collector{
+		m.readBytes, m.readSeconds, m.splitSeconds, m.chunks, m.chunkSizes, m.poolGets,
+	} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Chunk implements chunk.Metrics.
+func (m *Metrics) Chunk(size int) {
+	m.chunks.Inc()
+	m.chunkSizes.Observe(float64(size))
+}
+
+// Read implements chunk.Metrics.
+func (m *Metrics) Read(n int, d time.Duration) {
+	m.readBytes.Add(float64(n))
+	m.readSeconds.Add(d.Seconds())
+}
+
+// Split implements chunk.Metrics.
+func (m *Metrics) Split(d time.Duration) {
+	if d > 0 {
+		m.splitSeconds.Add(d.Seconds())
+	}
+}
+
+// PoolGet implements chunk.PoolMetrics.
+func (m *Metrics) PoolGet(hit bool) {
+	if hit {
+		m.poolGets.WithLabelValues("hit").Inc()
+	} else {
+		m.poolGets.WithLabelValues("miss").Inc()
+	}
+}
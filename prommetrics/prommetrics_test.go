@@ -0,0 +1,57 @@
+package prommetrics
+
+import (
+	"bytes"
+	"testing"
+
+	chunk "github.com/ipfs/go-ipfs-chunker"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var (
+	_ chunk.Metrics     = (*Metrics)(nil)
+	_ chunk.PoolMetrics = (*Metrics)(nil)
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := New(reg, prometheus.Labels{"chunker": "size-1024"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunk.SetPoolMetrics(m)
+	defer chunk.SetPoolMetrics(nil)
+
+	data := make([]byte, 10*1024+1)
+	s := chunk.NewInstrumentedSplitter(bytes.NewReader(data), chunk.SizeSplitterGen(1024), m)
+	for {
+		if _, err := s.NextBytes(); err != nil {
+			break
+		}
+	}
+
+	if v := testutil.ToFloat64(m.chunks); v != 11 {
+		t.Fatalf("expected 11 chunks, got %v", v)
+	}
+	if v := testutil.ToFloat64(m.readBytes); v != float64(len(data)) {
+		t.Fatalf("expected %d bytes read, got %v", len(data), v)
+	}
+	gets := testutil.ToFloat64(m.poolGets.WithLabelValues("hit")) + testutil.ToFloat64(m.poolGets.WithLabelValues("miss"))
+	if gets != 11 {
+		t.Fatalf("expected 11 pool gets, got %v", gets)
+	}
+
+	n, err := testutil.GatherAndCount(reg, "chunker_chunk_size_bytes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expected a chunk size histogram, got %d series", n)
+	}
+
+	if _, err := New(reg, prometheus.Labels{"chunker": "size-1024"}); err == nil {
+		t.Fatal("registering twice should fail")
+	}
+}
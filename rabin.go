@@ -18,7 +18,9 @@ var IpfsRabinPoly = chunker.Pol(17437180132763653)
 // Deprecated: use github.com/ipfs/boxo/chunker.Rabin
 type Rabin struct {
 	r      *chunker.Chunker
+	tttd   *rabinTTTD
 	reader io.Reader
+	trap   *readTrap
 }
 
 // NewRabin creates a new Rabin splitter with the given
@@ -38,16 +40,26 @@ func NewRabin(r io.Reader, avgBlkSize uint64) *Rabin {
 // Deprecated: use github.com/ipfs/boxo/chunker.NewRabinMinMax
 func NewRabinMinMax(r io.Reader, min, avg, max uint64) *Rabin {
 	h := fnv.New32a()
-	ch := chunker.New(r, IpfsRabinPoly, h, avg, min, max)
+	trap := newReadTrap(r)
+	ch := chunker.New(trap, IpfsRabinPoly, h, avg, min, max)
 
 	return &Rabin{
 		r:      ch,
 		reader: r,
+		trap:   trap,
 	}
 }
 
 // NextBytes reads the next bytes from the reader and returns a slice.
 func (r *Rabin) NextBytes() ([]byte, error) {
+	return r.trap.result(r.nextBytes())
+}
+
+func (r *Rabin) nextBytes() ([]byte, error) {
+	if r.tttd != nil {
+		return r.tttd.NextBytes()
+	}
+
 	ch, err := r.r.Next()
 	if err != nil {
 		return nil, err
@@ -56,6 +68,15 @@ func (r *Rabin) NextBytes() ([]byte, error) {
 	return ch.Data, nil
 }
 
+// MaxChunkSize returns the size at which chunks are cut when no boundary
+// is found.
+func (r *Rabin) MaxChunkSize() int {
+	if r.tttd != nil {
+		return r.tttd.max
+	}
+	return int(r.r.MaxSize)
+}
+
 // Reader returns the io.Reader associated to this Splitter.
 func (r *Rabin) Reader() io.Reader {
 	return r.reader
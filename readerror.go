@@ -0,0 +1,61 @@
+package chunk
+
+import (
+	"fmt"
+	"io"
+)
+
+// ReadError is returned by the splitters of this package when their reader
+// fails. Every byte read before the failure has been returned in a chunk
+// before the ReadError, so that a retrying importer can resume reading the
+// input at Offset.
+type ReadError struct {
+	// Offset is the number of bytes read from the input before the error.
+	Offset int64
+	Err    error
+}
+
+func (e *ReadError) Error() string {
+	return fmt.Sprintf("read error at offset %d: %s", e.Offset, e.Err)
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+// readTrap wraps the reader of a splitter and ends the stream with io.EOF
+// at the first read error, so that the splitter flushes what it buffered
+// as it would at the end of the input. Passing the results of NextBytes
+// through result then replaces the final io.EOF with a *ReadError.
+type readTrap struct {
+	r   io.Reader
+	off int64
+	err *ReadError
+}
+
+func newReadTrap(r io.Reader) *readTrap {
+	return &readTrap{r: r}
+}
+
+func (t *readTrap) Read(p []byte) (int, error) {
+	if t.err != nil {
+		return 0, io.EOF
+	}
+
+	n, err := t.r.Read(p)
+	t.off += int64(n)
+	if err != nil && err != io.EOF {
+		t.err = &ReadError{Offset: t.off, Err: err}
+		err = io.EOF
+	}
+	return n, err
+}
+
+// result returns the *ReadError in place of any error once the reader has
+// failed, as the splitter only saw a truncated input.
+func (t *readTrap) result(b []byte, err error) ([]byte, error) {
+	if err != nil && t.err != nil {
+		return nil, t.err
+	}
+	return b, err
+}
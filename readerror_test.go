@@ -0,0 +1,147 @@
+package chunk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+var errTestRead = errors.New("test read error")
+
+// failingReaderAt fails every read reaching past off once armed.
+type failingReaderAt struct {
+	r     io.ReaderAt
+	off   int64
+	armed bool
+}
+
+func (f *failingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if !f.armed || off+int64(len(p)) <= f.off {
+		return f.r.ReadAt(p, off)
+	}
+	n := 0
+	if off < f.off {
+		n, _ = f.r.ReadAt(p[:f.off-off], off)
+	}
+	return n, errTestRead
+}
+
+// checkReadError chunks s and checks that exactly the first at bytes of
+// data are delivered before a *ReadError at offset at.
+func checkReadError(t *testing.T, name string, s Splitter, data []byte, at int) {
+	t.Helper()
+
+	var got []byte
+	for {
+		b, err := s.NextBytes()
+		if err == nil {
+			got = append(got, b...)
+			continue
+		}
+
+		var rerr *ReadError
+		if !errors.As(err, &rerr) || !errors.Is(err, errTestRead) {
+			t.Fatalf("%s: expected a ReadError, got %v", name, err)
+		}
+		if rerr.Offset != int64(at) {
+			t.Fatalf("%s: error at offset %d, expected %d", name, rerr.Offset, at)
+		}
+		break
+	}
+	if !bytes.Equal(got, data[:at]) {
+		t.Fatalf("%s: got %d bytes before the error, expected %d", name, len(got), at)
+	}
+
+	if _, err := s.NextBytes(); !errors.Is(err, errTestRead) {
+		t.Fatalf("%s: the error is not returned again: %v", name, err)
+	}
+}
+
+func TestReadErrorAfterPartialData(t *testing.T) {
+	data := randBuf(t, 3<<20)
+	splitters := map[string]SplitterGen{
+		"size":         DefaultSplitter,
+		"rabin":        func(r io.Reader) Splitter { return NewRabin(r, 256<<10) },
+		"rabin-tttd":   func(r io.Reader) Splitter { return mustRabinTTTD(r, 64<<10, 256<<10, 384<<10) },
+		"buzhash":      func(r io.Reader) Splitter { return NewBuzhash(r) },
+		"buzhash-tttd": func(r io.Reader) Splitter { return NewBuzhashTTTD(r) },
+		"casync":       func(r io.Reader) Splitter { return NewCasync(r) },
+		"rollsum":      func(r io.Reader) Splitter { return mustRollsum(r, 13) },
+		"lines":        func(r io.Reader) Splitter { return NewRecordSplitter(r, 4096) },
+		"csv":          func(r io.Reader) Splitter { return NewCSVSplitter(r, 4096) },
+		"varint":       func(r io.Reader) Splitter { return mustVarintFramed(r, 4096) },
+		"tar":          func(r io.Reader) Splitter { return NewTarSplitter(r, DefaultSplitter) },
+		"gzip":         func(r io.Reader) Splitter { return NewGzipSplitter(r, DefaultSplitter) },
+		"mp4":          func(r io.Reader) Splitter { return NewMP4Splitter(r, DefaultSplitter) },
+		"sparse": func(r io.Reader) Splitter {
+			ss, err := NewSparseSplitter(r, 4096, DefaultSplitter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return ss
+		},
+		"merge": func(r io.Reader) Splitter { return MergeSmall(mustRollsum(r, 10), 4096) },
+		"aligned": func(r io.Reader) Splitter {
+			as, err := NewAlignedSplitter(r, 4096, 4, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return as
+		},
+		"oci-layer": func(r io.Reader) Splitter {
+			ls, err := NewOCILayerSplitter(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return ls
+		},
+	}
+
+	for _, at := range []int{1, 100000, 2<<20 + 1, len(data)} {
+		for name, gen := range splitters {
+			r := io.MultiReader(bytes.NewReader(data[:at]), iotest.ErrReader(errTestRead))
+			checkReadError(t, name, gen(r), data, at)
+		}
+	}
+}
+
+func TestReadErrorImmediate(t *testing.T) {
+	checkReadError(t, "buzhash", NewBuzhash(iotest.ErrReader(errTestRead)), nil, 0)
+
+	// Data returned together with the error is delivered too.
+	data := randBuf(t, 1000)
+	s := NewSizeSplitter(iotest.TimeoutReader(bytes.NewReader(data)), 100)
+	var got []byte
+	for {
+		b, err := s.NextBytes()
+		if err != nil {
+			var rerr *ReadError
+			if !errors.As(err, &rerr) || !errors.Is(err, iotest.ErrTimeout) {
+				t.Fatalf("expected a ReadError, got %v", err)
+			}
+			break
+		}
+		got = append(got, b...)
+	}
+	if len(got) == 0 || !bytes.Equal(got, data[:len(got)]) {
+		t.Fatalf("unexpected data before the error: %d bytes", len(got))
+	}
+}
+
+func TestReadErrorSection(t *testing.T) {
+	data := makeZip(t, []tarEntry{
+		{name: "a", body: randBuf(t, 1<<20)},
+		{name: "b", body: randBuf(t, 1<<20)},
+	}, "")
+	at := int64(1<<20 + 5000)
+
+	ra := &failingReaderAt{r: bytes.NewReader(data), off: at}
+	zs, err := NewZipSplitter(ra, int64(len(data)), DefaultSplitter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ra.armed = true
+	checkReadError(t, "zip", zs, data, int(at))
+}
@@ -0,0 +1,196 @@
+package chunk
+
+import (
+	"bytes"
+	"io"
+)
+
+// RecordSplitter implements the Splitter interface and produces chunks that
+// only end on record boundaries. Chunks end after the last delimiter within
+// the target size, or after the first one following it when a record is
+// larger than that. Records that do not fit into ChunkSizeLimit are cut
+// there.
+//
+// The buffer starts at twice the target size and only grows, up to
+// ChunkSizeLimit, while a record does not fit into it.
+type RecordSplitter struct {
+	r    io.Reader
+	trap *readTrap
+	size int
+	buf  []byte
+	n    int
+
+	// cut returns the length of the chunk at the start of buf, or -1 when
+	// buf contains no record boundary.
+	cut func(buf []byte, size int) int
+
+	err error
+}
+
+// NewRecordSplitter returns a RecordSplitter for newline delimited records
+// with the given target size.
+func NewRecordSplitter(r io.Reader, size int64) *RecordSplitter {
+	return NewRecordSplitterDelim(r, size, []byte{'\n'})
+}
+
+// NewRecordSplitterDelim returns a RecordSplitter for records terminated by
+// delim with the given target size.
+func NewRecordSplitterDelim(r io.Reader, size int64, delim []byte) *RecordSplitter {
+	if len(delim) == 0 {
+		delim = []byte{'\n'}
+	}
+	return newRecordSplitter(r, size, delimCut(delim))
+}
+
+// NewCSVSplitter returns a RecordSplitter for CSV data with the given target
+// size. Chunks only end after a newline that is not part of a quoted field.
+func NewCSVSplitter(r io.Reader, size int64) *RecordSplitter {
+	// inQuote tracks whether the next chunk starts within a quoted field,
+	// which only happens after a hard cut.
+	var inQuote bool
+	return newRecordSplitter(r, size, func(buf []byte, size int) int {
+		q := inQuote
+		last := -1
+		for i, c := range buf {
+			switch {
+			case c == '"':
+				q = !q
+			case c == '\n' && !q:
+				if i+1 > size {
+					if last < 0 {
+						last = i + 1
+					}
+					inQuote = false
+					return last
+				}
+				last = i + 1
+			}
+		}
+		if last >= 0 {
+			inQuote = false
+			return last
+		}
+		// The splitter only cuts without a boundary once buf has reached
+		// ChunkSizeLimit; before that it grows buf and asks again.
+		if len(buf) >= ChunkSizeLimit {
+			inQuote = q
+		}
+		return -1
+	})
+}
+
+func newRecordSplitter(r io.Reader, size int64, cut func([]byte, int) int) *RecordSplitter {
+	return &RecordSplitter{
+		r:    r,
+		trap: newReadTrap(r),
+		size: int(size),
+		buf:  getBuf(recordBufSize(int(size))),
+		cut:  cut,
+	}
+}
+
+// recordBufSize returns the initial buffer size for the given target size.
+func recordBufSize(size int) int {
+	n := 2 * size
+	if n < 4096 {
+		n = 4096
+	}
+	if n > ChunkSizeLimit {
+		n = ChunkSizeLimit
+	}
+	return n
+}
+
+func delimCut(delim []byte) func([]byte, int) int {
+	return func(buf []byte, size int) int {
+		if size > len(buf) {
+			size = len(buf)
+		}
+		if i := bytes.LastIndex(buf[:size], delim); i >= 0 {
+			return i + len(delim)
+		}
+
+		start := size - len(delim) + 1
+		if start < 0 {
+			start = 0
+		}
+		if i := bytes.Index(buf[start:], delim); i >= 0 {
+			return start + i + len(delim)
+		}
+		return -1
+	}
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (rs *RecordSplitter) Reader() io.Reader {
+	return rs.r
+}
+
+// NextBytes produces a new chunk.
+func (rs *RecordSplitter) NextBytes() ([]byte, error) {
+	return rs.trap.result(rs.nextBytes())
+}
+
+func (rs *RecordSplitter) nextBytes() ([]byte, error) {
+	if rs.err != nil {
+		return nil, rs.err
+	}
+
+	for {
+		n, err := io.ReadFull(rs.trap, rs.buf[rs.n:])
+		buffered := rs.n + n
+		if err != nil {
+			if err != io.ErrUnexpectedEOF && err != io.EOF {
+				rs.err = err
+				putBuf(rs.buf)
+				rs.buf = nil
+				return nil, err
+			}
+			if buffered <= rs.size {
+				rs.err = io.EOF
+				// Read nothing? Don't return an empty block.
+				if buffered == 0 {
+					putBuf(rs.buf)
+					rs.buf = nil
+					return nil, rs.err
+				}
+				res := make([]byte, buffered)
+				copy(res, rs.buf)
+
+				putBuf(rs.buf)
+				rs.buf = nil
+				return res, nil
+			}
+		}
+
+		i := rs.cut(rs.buf[:buffered], rs.size)
+		if i < 0 {
+			if err == nil && len(rs.buf) < ChunkSizeLimit {
+				// The record goes on past the buffer: grow it and read more.
+				rs.grow(buffered)
+				rs.n = buffered
+				continue
+			}
+			i = buffered
+		}
+
+		res := make([]byte, i)
+		copy(res, rs.buf)
+
+		rs.n = copy(rs.buf, rs.buf[i:buffered])
+
+		return res, nil
+	}
+}
+
+// grow doubles the buffer, up to ChunkSizeLimit, keeping its first n bytes.
+func (rs *RecordSplitter) grow(n int) {
+	size := 2 * len(rs.buf)
+	if size > ChunkSizeLimit {
+		size = ChunkSizeLimit
+	}
+	buf := getBuf(size)
+	copy(buf, rs.buf[:n])
+	putBuf(rs.buf)
+	rs.buf = buf
+}
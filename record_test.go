@@ -0,0 +1,128 @@
+package chunk
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func makeLines(n int) []byte {
+	rng := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, `{"id":%d,"pad":"%s"}`+"\n", i, bytes.Repeat([]byte("x"), rng.Intn(300)))
+	}
+	return buf.Bytes()
+}
+
+func TestRecordSplitter(t *testing.T) {
+	data := makeLines(20000)
+	const size = 4096
+
+	chunks := splitAll(t, NewRecordSplitter(bytes.NewReader(data), size))
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+	for i, chunk := range chunks {
+		if chunk[len(chunk)-1] != '\n' {
+			t.Fatalf("chunk %d/%d does not end on a record boundary", i+1, len(chunks))
+		}
+		if len(chunk) > size {
+			t.Fatalf("chunk %d/%d is larger than the target size", i+1, len(chunks))
+		}
+		if i < len(chunks)-1 && len(chunk) < size-400 {
+			t.Fatalf("chunk %d/%d is too small: %d", i+1, len(chunks), len(chunk))
+		}
+	}
+}
+
+func TestRecordSplitterLongRecords(t *testing.T) {
+	long := bytes.Repeat([]byte("a"), ChunkSizeLimit+100)
+	data := append([]byte("short\r\n"), long...)
+	data = append(data, "\r\nmedium-medium\r\ntail"...)
+
+	chunks := splitAll(t, NewRecordSplitterDelim(bytes.NewReader(data), 10, []byte("\r\n")))
+	got := chunkSizes(chunks)
+	want := []int{7, ChunkSizeLimit, 102, 15, 4}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected chunk sizes %v, got %v", want, got)
+	}
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+}
+
+func TestCSVSplitter(t *testing.T) {
+	var data []byte
+	for i := 0; len(data) < 1<<20; i++ {
+		data = append(data, "1,plain,\"quoted, with comma\"\n"...)
+		if i%7 == 0 {
+			data = append(data, "2,\"multi\nline\r\nfield with \"\"escaped\"\" quotes\",x\r\n"...)
+		}
+	}
+
+	const size = 4096
+	chunks := splitAll(t, NewCSVSplitter(bytes.NewReader(data), size))
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > size {
+			t.Fatalf("chunk %d/%d is larger than the target size", i+1, len(chunks))
+		}
+		if !bytes.HasPrefix(chunk, []byte("1,")) && !bytes.HasPrefix(chunk, []byte("2,")) {
+			t.Fatalf("chunk %d/%d does not start with a row: %q", i+1, len(chunks), chunk[:10])
+		}
+	}
+}
+
+func TestCSVSplitterHardCut(t *testing.T) {
+	// A quoted field longer than ChunkSizeLimit containing newlines forces a
+	// hard cut, after which the quote state must carry over.
+	field := bytes.Repeat([]byte("ab\n"), ChunkSizeLimit/2)
+	data := append([]byte("\""), field...)
+	data = append(data, "\"\nnext,row\nlast\n"...)
+
+	chunks := splitAll(t, NewCSVSplitter(bytes.NewReader(data), 10))
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+	if len(chunks) != 4 || len(chunks[0]) != ChunkSizeLimit || string(chunks[2]) != "next,row\n" {
+		t.Fatalf("unexpected chunk sizes %v", chunkSizes(chunks))
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	r := bytes.NewReader(randBuf(t, 1000))
+
+	if _, err := FromString(r, "lines-4096"); err != nil {
+		t.Fatalf("Expected success, got: %#v", err)
+	}
+	if _, err := FromString(r, "lines-0"); err != ErrSize {
+		t.Fatalf("Expected an 'ErrSize' error, got: %#v", err)
+	}
+	if _, err := FromString(r, fmt.Sprintf("lines-%d", 1+ChunkSizeLimit)); err != ErrSizeMax {
+		t.Fatalf("Expected 'ErrSizeMax', got: %#v", err)
+	}
+}
+
+func TestRecordSplitterGrowsBuffer(t *testing.T) {
+	long := append(bytes.Repeat([]byte("a"), 100<<10), '\n')
+	data := append([]byte("short\n"), long...)
+	data = append(data, "tail"...)
+
+	chunks := splitAll(t, NewRecordSplitter(bytes.NewReader(data), 64))
+	got := chunkSizes(chunks)
+	want := []int{6, len(long), 4}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected chunk sizes %v, got %v", want, got)
+	}
+}
+
+func BenchmarkRecordSplitter(b *testing.B) {
+	benchmarkChunker(b, func(r io.Reader) Splitter {
+		return NewRecordSplitter(r, 4096)
+	})
+}
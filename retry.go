@@ -0,0 +1,170 @@
+package chunk
+
+import (
+	"errors"
+	"io"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 5
+	defaultMinBackoff = 100 * time.Millisecond
+	defaultMaxBackoff = 10 * time.Second
+)
+
+// RetryOptions configures a RetryingSplitter. The zero value uses the
+// defaults documented on each field.
+type RetryOptions struct {
+	// Retryable reports whether a read error is transient. By default,
+	// EIO, EAGAIN and errors with a Timeout() method returning true are.
+	Retryable func(error) bool
+	// MaxRetries is the number of consecutive failed reads retried before
+	// giving up, 5 by default.
+	MaxRetries int
+	// MinBackoff is the wait before the first retry, 100ms by default. It
+	// doubles with every consecutive failure, up to MaxBackoff (10s by
+	// default).
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Done, when closed, cuts the wait before a retry short: the read fails
+	// with its last error instead. Pass ctx.Done() to stop retrying once a
+	// context is cancelled.
+	Done <-chan struct{}
+}
+
+// RetryingSplitter implements the Splitter interface on top of another
+// splitter, retrying the reads that fail with a transient error. Reads are
+// resumed at the exact offset at which they failed, so the chunks are the
+// same as if no error had occurred. When the retries are exhausted, the
+// inner splitter returns a *ReadError as usual.
+type RetryingSplitter struct {
+	r  io.Reader
+	rr *retryReader
+	s  Splitter
+}
+
+// NewRetryingSplitter returns a RetryingSplitter splitting r with gen. r
+// must implement io.ReaderAt or io.Seeker. It is read from its current
+// position when it implements io.Seeker, and from offset 0 otherwise.
+func NewRetryingSplitter(r io.Reader, gen SplitterGen, opts RetryOptions) (*RetryingSplitter, error) {
+	if opts.Retryable == nil {
+		opts.Retryable = isTransient
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = defaultMaxRetries
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = defaultMinBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultMaxBackoff
+	}
+
+	rr := &retryReader{opts: opts}
+	rr.ra, _ = r.(io.ReaderAt)
+	if rs, ok := r.(io.ReadSeeker); ok {
+		off, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		rr.off = off
+		if rr.ra == nil {
+			rr.rs = rs
+		}
+	} else if rr.ra == nil {
+		return nil, ErrNotSeekable
+	}
+
+	return &RetryingSplitter{
+		r:  r,
+		rr: rr,
+		s:  gen(rr),
+	}, nil
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (rs *RetryingSplitter) Reader() io.Reader {
+	return rs.r
+}
+
+// NextBytes produces a new chunk.
+func (rs *RetryingSplitter) NextBytes() ([]byte, error) {
+	return rs.s.NextBytes()
+}
+
+// Retries returns the number of reads that have been retried so far.
+func (rs *RetryingSplitter) Retries() int {
+	return rs.rr.retries
+}
+
+// isTransient is the default RetryOptions.Retryable.
+func isTransient(err error) bool {
+	if errors.Is(err, syscall.EIO) || errors.Is(err, syscall.EAGAIN) {
+		return true
+	}
+	var te interface{ Timeout() bool }
+	return errors.As(err, &te) && te.Timeout()
+}
+
+// retryReader reads sequentially from an io.ReaderAt or io.ReadSeeker,
+// retrying transient errors.
+type retryReader struct {
+	ra   io.ReaderAt
+	rs   io.ReadSeeker
+	off  int64
+	opts RetryOptions
+
+	reseek  bool
+	retries int
+}
+
+func (rr *retryReader) Read(p []byte) (int, error) {
+	backoff := rr.opts.MinBackoff
+	for failures := 0; ; failures++ {
+		n, err := rr.read(p)
+		rr.off += int64(n)
+		if err == nil || err == io.EOF || !rr.opts.Retryable(err) {
+			return n, err
+		}
+		// The position of a failed reader is unknown.
+		rr.reseek = rr.rs != nil
+		// Return what was read, the next read retries from there.
+		if n > 0 {
+			return n, nil
+		}
+		if failures == rr.opts.MaxRetries {
+			return 0, err
+		}
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-rr.opts.Done:
+			t.Stop()
+			return 0, err
+		}
+		if backoff *= 2; backoff > rr.opts.MaxBackoff {
+			backoff = rr.opts.MaxBackoff
+		}
+		rr.retries++
+	}
+}
+
+func (rr *retryReader) read(p []byte) (int, error) {
+	if rr.ra == nil {
+		if rr.reseek {
+			if _, err := rr.rs.Seek(rr.off, io.SeekStart); err != nil {
+				return 0, err
+			}
+			rr.reseek = false
+		}
+		return rr.rs.Read(p)
+	}
+	n, err := rr.ra.ReadAt(p, rr.off)
+	// ReadAt reports io.EOF along with the last bytes.
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
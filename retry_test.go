@@ -0,0 +1,204 @@
+package chunk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyReader fails every other read with err, after returning part of the
+// data on some of them, and moves the read position on failures.
+type flakyReader struct {
+	r     *bytes.Reader
+	err   error
+	calls int
+}
+
+func (f *flakyReader) Read(p []byte) (int, error) {
+	f.calls++
+	if f.calls%2 == 0 {
+		n := 0
+		if f.calls%4 == 0 && len(p) > 10 {
+			n, _ = f.r.Read(p[:10])
+		}
+		// Garble the position, the reader must seek back.
+		f.r.Seek(1000, io.SeekCurrent)
+		return n, f.err
+	}
+	return f.r.Read(p)
+}
+
+func (f *flakyReader) Seek(off int64, whence int) (int64, error) {
+	return f.r.Seek(off, whence)
+}
+
+// flakyReaderAt fails every other read with err.
+type flakyReaderAt struct {
+	r     *bytes.Reader
+	err   error
+	calls int
+}
+
+func (f *flakyReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	f.calls++
+	if f.calls%2 == 0 {
+		return 0, f.err
+	}
+	return f.r.ReadAt(p, off)
+}
+
+func (f *flakyReaderAt) Read(p []byte) (int, error) {
+	panic("Read should not be used")
+}
+
+var fastRetry = RetryOptions{MinBackoff: time.Microsecond, MaxBackoff: time.Millisecond}
+
+func TestRetryingSplitter(t *testing.T) {
+	data := randBuf(t, 4<<20)
+	want := splitAll(t, NewBuzhash(bytes.NewReader(data)))
+	gen := func(r io.Reader) Splitter { return NewBuzhash(r) }
+
+	for name, r := range map[string]io.Reader{
+		"ReadSeeker": &flakyReader{r: bytes.NewReader(data), err: syscall.EIO},
+		"ReaderAt":   &flakyReaderAt{r: bytes.NewReader(data), err: syscall.EIO},
+	} {
+		rs, err := NewRetryingSplitter(r, gen, fastRetry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := splitAll(t, rs)
+		if len(got) != len(want) {
+			t.Fatalf("%s: got %d chunks, expected %d", name, len(got), len(want))
+		}
+		for i := range got {
+			if !bytes.Equal(got[i], want[i]) {
+				t.Fatalf("%s: chunk %d differs", name, i)
+			}
+		}
+		if rs.Retries() == 0 {
+			t.Fatalf("%s: no read was retried", name)
+		}
+		if rs.Reader() != r {
+			t.Fatalf("%s: Reader does not return the original reader", name)
+		}
+	}
+}
+
+func TestRetryingSplitterStartOffset(t *testing.T) {
+	data := randBuf(t, 10000)
+	r := bytes.NewReader(data)
+	r.Seek(1000, io.SeekStart)
+
+	rs, err := NewRetryingSplitter(&flakyReader{r: r, err: syscall.EIO}, SizeSplitterGen(1024), fastRetry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bytes.Join(splitAll(t, rs), nil); !bytes.Equal(got, data[1000:]) {
+		t.Fatal("the input was not read from its current position")
+	}
+}
+
+func TestRetryingSplitterReaderAtOffset(t *testing.T) {
+	data := randBuf(t, 10000)
+	r := bytes.NewReader(data)
+	r.Seek(1000, io.SeekStart)
+
+	// *bytes.Reader is both an io.ReaderAt and an io.Seeker.
+	rs, err := NewRetryingSplitter(r, SizeSplitterGen(1024), fastRetry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bytes.Join(splitAll(t, rs), nil); !bytes.Equal(got, data[1000:]) {
+		t.Fatal("the input was not read from its current position")
+	}
+}
+
+// brokenReaderAt fails every read past off with err.
+type brokenReaderAt struct {
+	r   *bytes.Reader
+	off int64
+	err error
+}
+
+func (b *brokenReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= b.off {
+		return 0, b.err
+	}
+	if max := b.off - off; int64(len(p)) > max {
+		p = p[:max]
+	}
+	return b.r.ReadAt(p, off)
+}
+
+func (b *brokenReaderAt) Read(p []byte) (int, error) {
+	panic("Read should not be used")
+}
+
+func TestRetryingSplitterGivesUp(t *testing.T) {
+	data := randBuf(t, 100000)
+	errOther := errors.New("permanent")
+
+	for _, c := range []struct {
+		err     error
+		retries int
+	}{
+		{syscall.EIO, 3},
+		{errOther, 0},
+	} {
+		opts := fastRetry
+		opts.MaxRetries = 3
+		rs, err := NewRetryingSplitter(&brokenReaderAt{r: bytes.NewReader(data), off: 5000, err: c.err}, SizeSplitterGen(1024), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var n int
+		for {
+			b, err := rs.NextBytes()
+			if err == nil {
+				n += len(b)
+				continue
+			}
+			var rerr *ReadError
+			if !errors.As(err, &rerr) || !errors.Is(err, c.err) || rerr.Offset != 5000 {
+				t.Fatalf("expected a ReadError at offset 5000, got %v", err)
+			}
+			break
+		}
+		if n != 5000 {
+			t.Fatalf("expected 5000 bytes before the error, got %d", n)
+		}
+		if rs.Retries() != c.retries {
+			t.Fatalf("expected %d retries, got %d", c.retries, rs.Retries())
+		}
+	}
+}
+
+func TestRetryingSplitterDone(t *testing.T) {
+	data := randBuf(t, 10000)
+	done := make(chan struct{})
+	opts := RetryOptions{MinBackoff: time.Hour, Done: done}
+	rs, err := NewRetryingSplitter(&brokenReaderAt{r: bytes.NewReader(data), off: 0, err: syscall.EIO}, SizeSplitterGen(1024), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.AfterFunc(10*time.Millisecond, func() { close(done) })
+	start := time.Now()
+	if _, err := rs.NextBytes(); !errors.Is(err, syscall.EIO) {
+		t.Fatalf("expected the read error, got %v", err)
+	}
+	if d := time.Since(start); d > time.Minute {
+		t.Fatalf("closing Done did not stop the backoff, waited %s", d)
+	}
+}
+
+func TestRetryingSplitterNotSeekable(t *testing.T) {
+	_, err := NewRetryingSplitter(io.LimitReader(bytes.NewReader(nil), 0), DefaultSplitter, RetryOptions{})
+	if err != ErrNotSeekable {
+		t.Fatalf("expected ErrNotSeekable, got %v", err)
+	}
+}
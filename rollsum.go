@@ -0,0 +1,120 @@
+package chunk
+
+import (
+	"io"
+)
+
+const (
+	// rollsumWindow is the size of the rolling checksum window used by
+	// rsync and bup.
+	rollsumWindow     = 64
+	rollsumCharOffset = 31
+
+	rollsumMinBits     = 6
+	rollsumMaxBits     = 16
+	rollsumDefaultBits = 13
+	// rollsumMaxFactor bounds chunks to this many times the average size,
+	// which for 13 bits matches bup's 32KiB blob limit.
+	rollsumMaxFactor = 4
+)
+
+// Rollsum implements the Splitter interface and splits content with the
+// Adler-32 style rolling checksum used by rsync and bup. A chunk ends after
+// the byte at which the low bits of the checksum's second sum are all ones.
+type Rollsum struct {
+	r    io.Reader
+	trap *readTrap
+	buf  []byte
+	n    int
+	mask uint32
+	max  int
+
+	err error
+}
+
+// NewRollsum returns a Rollsum splitter producing chunks of 2^bits bytes on
+// average and at most four times that. bits must be between 6 and 16, as the
+// checksum is not uniform enough to find larger averages.
+func NewRollsum(r io.Reader, bits uint) (*Rollsum, error) {
+	if bits < rollsumMinBits || bits > rollsumMaxBits {
+		return nil, ErrRollsumBits
+	}
+
+	max := rollsumMaxFactor << bits
+	return &Rollsum{
+		r:    r,
+		trap: newReadTrap(r),
+		buf:  getBuf(max),
+		mask: 1<<bits - 1,
+		max:  max,
+	}, nil
+}
+
+// MaxChunkSize returns the size at which chunks are cut when no boundary
+// is found.
+func (rs *Rollsum) MaxChunkSize() int {
+	return rs.max
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (rs *Rollsum) Reader() io.Reader {
+	return rs.r
+}
+
+// NextBytes produces a new chunk.
+func (rs *Rollsum) NextBytes() ([]byte, error) {
+	return rs.trap.result(rs.nextBytes())
+}
+
+func (rs *Rollsum) nextBytes() ([]byte, error) {
+	if rs.err != nil {
+		return nil, rs.err
+	}
+
+	n, err := io.ReadFull(rs.trap, rs.buf[rs.n:])
+	buffered := rs.n + n
+	if err != nil {
+		if err != io.ErrUnexpectedEOF && err != io.EOF {
+			rs.err = err
+			putBuf(rs.buf)
+			rs.buf = nil
+			return nil, err
+		}
+		if buffered == 0 {
+			rs.err = io.EOF
+			putBuf(rs.buf)
+			rs.buf = nil
+			return nil, rs.err
+		}
+	}
+
+	i := rs.boundary(rs.buf[:buffered])
+
+	res := make([]byte, i)
+	copy(res, rs.buf)
+
+	rs.n = copy(rs.buf, rs.buf[i:buffered])
+
+	return res, nil
+}
+
+// boundary returns the length of the chunk at the start of buf, or len(buf)
+// if it contains no boundary.
+func (rs *Rollsum) boundary(buf []byte) int {
+	var window [rollsumWindow]byte
+	s1 := uint32(rollsumWindow * rollsumCharOffset)
+	s2 := uint32(rollsumWindow * (rollsumWindow - 1) * rollsumCharOffset)
+
+	for i, b := range buf {
+		drop := uint32(window[i%rollsumWindow])
+		window[i%rollsumWindow] = b
+
+		s1 += uint32(b) - drop
+		s2 += s1 - rollsumWindow*(drop+rollsumCharOffset)
+
+		if s2&rs.mask == rs.mask {
+			return i + 1
+		}
+	}
+	return len(buf)
+}
@@ -0,0 +1,96 @@
+package chunk
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// rollsumDigest computes bup's second rolling sum for the window ending at
+// the end of data from scratch, padding it with zeros like a fresh rollsum.
+func rollsumDigest(data []byte) uint32 {
+	var window [rollsumWindow]byte
+	if len(data) >= rollsumWindow {
+		copy(window[:], data[len(data)-rollsumWindow:])
+	} else {
+		copy(window[rollsumWindow-len(data):], data)
+	}
+
+	// The initial value of s2 carries this constant on top of the
+	// position-weighted sum of the window.
+	s2 := uint32(rollsumWindow*(rollsumWindow-1)*rollsumCharOffset - rollsumWindow*(rollsumWindow+1)/2*rollsumCharOffset)
+	for i, b := range window {
+		s2 += uint32(rollsumWindow-i) * (uint32(b) + rollsumCharOffset)
+	}
+	return s2
+}
+
+func mustRollsum(r io.Reader, bits uint) *Rollsum {
+	s, err := NewRollsum(r, bits)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func TestRollsumChunking(t *testing.T) {
+	data := randBuf(t, 1<<20)
+	const bits = 10
+	mask := uint32(1<<bits - 1)
+
+	r := mustRollsum(bytes.NewReader(data), bits)
+
+	var chunks [][]byte
+	for {
+		chunk, err := r.NextBytes()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+
+	for i, chunk := range chunks {
+		if len(chunk) > rollsumMaxFactor<<bits {
+			t.Fatalf("chunk %d/%d is larger than the maximum size", i+1, len(chunks))
+		}
+		for j := 1; j < len(chunk); j++ {
+			if rollsumDigest(chunk[:j])&mask == mask {
+				t.Fatalf("chunk %d/%d missed a boundary at %d", i+1, len(chunks), j)
+			}
+		}
+		if i == len(chunks)-1 || len(chunk) == rollsumMaxFactor<<bits {
+			continue
+		}
+		if rollsumDigest(chunk)&mask != mask {
+			t.Fatalf("chunk %d/%d does not end on a boundary", i+1, len(chunks))
+		}
+	}
+}
+
+func TestRollsumChunkReuse(t *testing.T) {
+	newRollsum := func(r io.Reader) Splitter {
+		return mustRollsum(r, rollsumDefaultBits)
+	}
+	testReuse(t, newRollsum)
+}
+
+func BenchmarkRollsum(b *testing.B) {
+	benchmarkChunker(b, func(r io.Reader) Splitter {
+		return mustRollsum(r, rollsumDefaultBits)
+	})
+}
+
+func TestRollsumInvalid(t *testing.T) {
+	for _, bits := range []uint{0, rollsumMinBits - 1, rollsumMaxBits + 1, 64} {
+		if _, err := NewRollsum(bytes.NewReader(nil), bits); err != ErrRollsumBits {
+			t.Fatalf("bits %d: expected ErrRollsumBits, got %v", bits, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package chunk
+
+import (
+	"io"
+	"sort"
+)
+
+// sectionSplitter splits the content of an io.ReaderAt into sections
+// between the given cut offsets, each of which is split by an inner
+// splitter.
+type sectionSplitter struct {
+	r     io.ReaderAt
+	size  int64
+	inner SplitterGen
+
+	// cuts are the offsets at which a new inner splitter is started.
+	cuts []int64
+	cur  Splitter
+	trap *readTrap
+
+	err error
+}
+
+// newSectionSplitter returns a sectionSplitter which cuts at the given
+// offsets, ignoring those that are out of range.
+func newSectionSplitter(r io.ReaderAt, size int64, inner SplitterGen, cuts []int64) sectionSplitter {
+	valid := []int64{0, size}
+	for _, cut := range cuts {
+		if cut > 0 && cut < size {
+			valid = append(valid, cut)
+		}
+	}
+	cuts = valid
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i] < cuts[j] })
+
+	return sectionSplitter{
+		r:     r,
+		size:  size,
+		inner: inner,
+		cuts:  cuts,
+	}
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (ss *sectionSplitter) Reader() io.Reader {
+	return io.NewSectionReader(ss.r, 0, ss.size)
+}
+
+// NextBytes produces a new chunk.
+func (ss *sectionSplitter) NextBytes() ([]byte, error) {
+	if ss.err != nil {
+		return nil, ss.err
+	}
+
+	for {
+		if ss.cur != nil {
+			b, err := ss.cur.NextBytes()
+			if err != nil && ss.trap.err != nil {
+				err = ss.trap.err
+			}
+			if err == nil {
+				return b, nil
+			} else if err != io.EOF {
+				ss.err = err
+				return nil, err
+			}
+			ss.cur = nil
+		}
+
+		if len(ss.cuts) < 2 {
+			ss.err = io.EOF
+			return nil, ss.err
+		}
+		start, end := ss.cuts[0], ss.cuts[1]
+		ss.cuts = ss.cuts[1:]
+		if start < end {
+			// Offsets of read errors are relative to the whole input.
+			ss.trap = &readTrap{r: io.NewSectionReader(ss.r, start, end-start), off: start}
+			ss.cur = ss.inner(ss.trap)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package chunk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+var zeroPage [32 << 10]byte
+
+// errNoSeekData is returned by seekData on platforms without SEEK_DATA.
+var errNoSeekData = errors.New("SEEK_DATA is not supported")
+
+// SparseSplitter implements the Splitter interface for inputs holding
+// large zero regions, like disk images and VM snapshots. The input is read
+// in blocks of a fixed size, aligned to its start: blocks which only
+// contain zeros are emitted as zero chunks of their own, and runs of other
+// blocks are split by an inner splitter.
+//
+// NextChunk reports zero chunks by their length alone, so that downstream
+// code can store a single canonical zero block instead of hashing them.
+// When reading from an *os.File on a platform that supports it, holes are
+// found with SEEK_DATA and SEEK_HOLE, and the zero chunks they cover are
+// produced without reading them.
+type SparseSplitter struct {
+	r     io.Reader
+	size  int64
+	inner SplitterGen
+
+	cur   Splitter
+	zeros int
+
+	f        *os.File
+	holes    bool
+	start    int64
+	off      int64
+	fileSize int64
+	dataOff  int64
+	holeOff  int64
+	seek     bool
+
+	err error
+}
+
+// NewSparseSplitter returns a SparseSplitter detecting zero blocks of the
+// given size, which splits the data between them with the given inner
+// splitter.
+func NewSparseSplitter(r io.Reader, size int64, inner SplitterGen) (*SparseSplitter, error) {
+	if size <= 0 {
+		return nil, ErrSize
+	} else if size > int64(ChunkSizeLimit) {
+		return nil, ErrSizeMax
+	}
+
+	ss := &SparseSplitter{
+		r:     r,
+		size:  size,
+		inner: inner,
+	}
+
+	if f, ok := r.(*os.File); ok {
+		off, err := f.Seek(0, io.SeekCurrent)
+		fi, serr := f.Stat()
+		if err == nil && serr == nil && fi.Mode().IsRegular() {
+			ss.f = f
+			ss.holes = true
+			ss.start = off
+			ss.off = off
+			ss.dataOff = off
+			ss.holeOff = off
+			ss.fileSize = fi.Size()
+		}
+	}
+	return ss, nil
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (ss *SparseSplitter) Reader() io.Reader {
+	return ss.r
+}
+
+// NextBytes produces a new chunk. Zero chunks are allocated, use NextChunk
+// to avoid it.
+func (ss *SparseSplitter) NextBytes() ([]byte, error) {
+	b, zeros, err := ss.NextChunk()
+	if zeros > 0 {
+		b = make([]byte, zeros)
+	}
+	return b, err
+}
+
+// NextChunk produces a new chunk. A zero chunk is returned as its length
+// in zeros, with a nil slice.
+func (ss *SparseSplitter) NextChunk() (b []byte, zeros int, err error) {
+	for {
+		if ss.cur != nil {
+			b, err := ss.cur.NextBytes()
+			if err != io.EOF {
+				if err != nil {
+					ss.err = err
+				}
+				return b, 0, err
+			}
+			ss.cur = nil
+		}
+
+		if ss.zeros > 0 {
+			zeros, ss.zeros = ss.zeros, 0
+			return nil, zeros, nil
+		}
+		if ss.err != nil {
+			return nil, 0, ss.err
+		}
+
+		b, zeros, err := ss.readBlock()
+		if err != nil {
+			return nil, 0, err
+		} else if zeros > 0 {
+			return nil, zeros, nil
+		}
+		ss.cur = ss.inner(&sparseRun{ss: ss, buf: b, full: b})
+	}
+}
+
+// readBlock reads the next block of the input. A block which only contains
+// zeros is returned as its length, with a nil slice.
+func (ss *SparseSplitter) readBlock() ([]byte, int, error) {
+	if ss.err != nil {
+		return nil, 0, ss.err
+	}
+
+	if ss.holes {
+		if n := ss.hole(); n > 0 {
+			ss.off += n
+			ss.seek = true
+			return nil, int(n), nil
+		}
+	}
+	if ss.seek {
+		if _, err := ss.f.Seek(ss.off, io.SeekStart); err != nil {
+			ss.err = &ReadError{Offset: ss.off - ss.start, Err: err}
+			return nil, 0, ss.err
+		}
+		ss.seek = false
+	}
+
+	full := getBuf(int(ss.size))
+	n, err := io.ReadFull(ss.r, full)
+	ss.off += int64(n)
+	switch err {
+	case nil:
+	case io.ErrUnexpectedEOF:
+		ss.err = io.EOF
+	case io.EOF:
+		ss.err = err
+		putBuf(full)
+		return nil, 0, err
+	default:
+		// Deliver what was read before the error.
+		ss.err = &ReadError{Offset: ss.off - ss.start, Err: err}
+		if n == 0 {
+			putBuf(full)
+			return nil, 0, ss.err
+		}
+	}
+
+	if isZero(full[:n]) {
+		putBuf(full)
+		return nil, n, nil
+	}
+	return full[:n], 0, nil
+}
+
+// hole returns the length of the zero chunk at the current offset if it
+// lies entirely within a hole of the file, or 0.
+func (ss *SparseSplitter) hole() int64 {
+	if ss.off >= ss.holeOff {
+		// Past the data found last time, look for the next one.
+		dataOff, holeOff, err := seekData(ss.f, ss.off)
+		// Fall back to reading when SEEK_DATA is not available.
+		if err != nil {
+			ss.holes = false
+			return 0
+		}
+		if dataOff < 0 {
+			dataOff, holeOff = ss.fileSize, ss.fileSize
+		}
+		ss.seek = true
+		ss.dataOff = dataOff
+		ss.holeOff = holeOff
+	}
+
+	n := ss.size
+	if rest := ss.fileSize - ss.off; rest < n {
+		n = rest
+	}
+	if n <= 0 || ss.dataOff < ss.off+n {
+		return 0
+	}
+	return n
+}
+
+// sparseRun reads a run of data blocks for the inner splitter, and ends at
+// the first zero block, which is left for the SparseSplitter to emit. Like
+// a readTrap, it also ends at read errors.
+type sparseRun struct {
+	ss   *SparseSplitter
+	buf  []byte
+	full []byte
+	done bool
+}
+
+func (sr *sparseRun) Read(p []byte) (int, error) {
+	if len(sr.buf) == 0 {
+		if sr.full != nil {
+			putBuf(sr.full)
+			sr.full = nil
+		}
+		if sr.done {
+			return 0, io.EOF
+		}
+		b, zeros, err := sr.ss.readBlock()
+		if err != nil || zeros > 0 {
+			sr.ss.zeros = zeros
+			sr.done = true
+			return 0, io.EOF
+		}
+		sr.buf, sr.full = b, b
+	}
+
+	n := copy(p, sr.buf)
+	sr.buf = sr.buf[n:]
+	return n, nil
+}
+
+func isZero(b []byte) bool {
+	for len(b) > 0 {
+		n := len(b)
+		if n > len(zeroPage) {
+			n = len(zeroPage)
+		}
+		if !bytes.Equal(b[:n], zeroPage[:n]) {
+			return false
+		}
+		b = b[n:]
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+package chunk
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// whenceData and whenceHole are SEEK_DATA and SEEK_HOLE on Linux.
+const (
+	whenceData = 3
+	whenceHole = 4
+)
+
+// seekData returns the offset of the first byte of data at or after off,
+// and the offset of the hole which follows it, or -1 if there is no data.
+func seekData(f *os.File, off int64) (int64, int64, error) {
+	dataOff, err := f.Seek(off, whenceData)
+	if errors.Is(err, syscall.ENXIO) {
+		return -1, -1, nil
+	} else if err != nil {
+		return 0, 0, err
+	}
+	holeOff, err := f.Seek(dataOff, whenceHole)
+	if err != nil {
+		return 0, 0, err
+	}
+	return dataOff, holeOff, nil
+}
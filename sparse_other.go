@@ -0,0 +1,11 @@
+//go:build !linux
+
+package chunk
+
+import (
+	"os"
+)
+
+func seekData(f *os.File, off int64) (int64, int64, error) {
+	return 0, 0, errNoSeekData
+}
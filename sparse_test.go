@@ -0,0 +1,146 @@
+package chunk
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func sparseSplitAll(t *testing.T, ss *SparseSplitter) ([][]byte, []bool) {
+	var chunks [][]byte
+	var zeros []bool
+	for {
+		b, n, err := ss.NextChunk()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		if n > 0 {
+			if b != nil {
+				t.Fatalf("zero chunk %d has a buffer", len(chunks))
+			}
+			b = make([]byte, n)
+		} else if len(b) == 0 {
+			t.Fatalf("chunk %d is empty", len(chunks))
+		}
+		chunks = append(chunks, b)
+		zeros = append(zeros, n > 0)
+	}
+	return chunks, zeros
+}
+
+func newSparseSplitter(t *testing.T, r io.Reader, size int64, inner SplitterGen) *SparseSplitter {
+	ss, err := NewSparseSplitter(r, size, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ss
+}
+
+func TestSparseSplitter(t *testing.T) {
+	const size = 64 << 10
+	data := make([]byte, 20*size+100)
+	copy(data[3*size:], randBuf(t, 3*size))
+	copy(data[10*size+5:], randBuf(t, 10))
+
+	chunks, zeros := sparseSplitAll(t, newSparseSplitter(t, bytes.NewReader(data), size, SizeSplitterGen(50000)))
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+	var count int
+	for _, zero := range zeros {
+		if zero {
+			count++
+		}
+	}
+	if count != 17 {
+		t.Fatalf("expected 17 zero chunks, got %d", count)
+	}
+	// The data blocks are split by the inner splitter, each run on its own.
+	if sizes := chunkSizes(chunks[3:8]); sizes[0] != 50000 || sizes[3] != 3*size-150000 || len(chunks[8]) != size {
+		t.Fatalf("unexpected chunk sizes %v", chunkSizes(chunks))
+	}
+}
+
+func TestSparseSplitterInvalid(t *testing.T) {
+	for _, size := range []int64{0, -1} {
+		if _, err := NewSparseSplitter(bytes.NewReader(nil), size, DefaultSplitter); err != ErrSize {
+			t.Fatalf("size %d: expected ErrSize, got %v", size, err)
+		}
+	}
+	if _, err := NewSparseSplitter(bytes.NewReader(nil), int64(ChunkSizeLimit)+1, DefaultSplitter); err != ErrSizeMax {
+		t.Fatalf("expected ErrSizeMax, got %v", err)
+	}
+}
+
+func TestSparseSplitterFile(t *testing.T) {
+	const size = 64 << 10
+	path := filepath.Join(t.TempDir(), "sparse")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	payload := randBuf(t, 3000)
+	if _, err := f.WriteAt(payload, 100*size+10); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(400*size + 123); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Start past the beginning of the file to check that offsets are kept.
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	chunks, zeros := sparseSplitAll(t, newSparseSplitter(t, f, size, DefaultSplitter))
+	if !bytes.Equal(bytes.Join(chunks, nil), data[size:]) {
+		t.Fatal("data was chunked incorrectly")
+	}
+	for i, zero := range zeros {
+		if zero == (i == 99) {
+			t.Fatalf("chunk %d reported as zero=%v", i, zero)
+		}
+	}
+}
+
+func TestSparseSplitterFileReadError(t *testing.T) {
+	const size = 4096
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, randBuf(t, 3*size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	ss := newSparseSplitter(t, f, size, SizeSplitterGen(size))
+	if _, _, err := ss.NextChunk(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	_, _, err = ss.NextChunk()
+	re, ok := err.(*ReadError)
+	if !ok {
+		t.Fatalf("expected a ReadError, got %v", err)
+	}
+	// Offsets are relative to where the splitter started.
+	if re.Offset != size {
+		t.Fatalf("expected the error at offset %d, got %d", size, re.Offset)
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"io"
 
 	logging "github.com/ipfs/go-log"
-	pool "github.com/libp2p/go-buffer-pool"
 )
 
 var log = logging.Logger("chunk")
@@ -16,6 +15,9 @@ var log = logging.Logger("chunk")
 // A Splitter reads bytes from a Reader and creates "chunks" (byte slices)
 // that can be used to build DAG nodes.
 //
+// When the reader fails, the splitters of this package first return every
+// byte read before the failure, then a *ReadError.
+//
 // Deprecated: use github.com/ipfs/boxo/chunker.Splitter
 type Splitter interface {
 	Reader() io.Reader
@@ -71,6 +73,7 @@ func Chan(s Splitter) (<-chan []byte, <-chan error) {
 
 type sizeSplitterv2 struct {
 	r    io.Reader
+	trap *readTrap
 	size uint32
 	err  error
 }
@@ -81,33 +84,43 @@ type sizeSplitterv2 struct {
 func NewSizeSplitter(r io.Reader, size int64) Splitter {
 	return &sizeSplitterv2{
 		r:    r,
+		trap: newReadTrap(r),
 		size: uint32(size),
 	}
 }
 
 // NextBytes produces a new chunk.
 func (ss *sizeSplitterv2) NextBytes() ([]byte, error) {
+	return ss.trap.result(ss.nextBytes())
+}
+
+func (ss *sizeSplitterv2) nextBytes() ([]byte, error) {
 	if ss.err != nil {
 		return nil, ss.err
 	}
 
-	full := pool.Get(int(ss.size))
-	n, err := io.ReadFull(ss.r, full)
+	full := getBuf(int(ss.size))
+	n, err := io.ReadFull(ss.trap, full)
 	switch err {
 	case io.ErrUnexpectedEOF:
 		ss.err = io.EOF
 		small := make([]byte, n)
 		copy(small, full)
-		pool.Put(full)
+		putBuf(full)
 		return small, nil
 	case nil:
 		return full, nil
 	default:
-		pool.Put(full)
+		putBuf(full)
 		return nil, err
 	}
 }
 
+// MaxChunkSize returns the size of the chunks produced by the splitter.
+func (ss *sizeSplitterv2) MaxChunkSize() int {
+	return int(ss.size)
+}
+
 // Reader returns the io.Reader associated to this Splitter.
 func (ss *sizeSplitterv2) Reader() io.Reader {
 	return ss.r
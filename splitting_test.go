@@ -8,7 +8,7 @@ import (
 	u "github.com/ipfs/go-ipfs-util"
 )
 
-func randBuf(t *testing.T, size int) []byte {
+func randBuf(t testing.TB, size int) []byte {
 	buf := make([]byte, size)
 	if _, err := u.NewTimeSeededRand().Read(buf); err != nil {
 		t.Fatal("failed to read enough randomness")
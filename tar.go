@@ -0,0 +1,157 @@
+package chunk
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const tarBlockSize = 512
+
+// TarSplitter implements the Splitter interface for tar archives. Every
+// header block is emitted as a chunk of its own and every member payload
+// (including its padding) is split separately by an inner splitter, so
+// identical files stored in different archives produce identical chunks.
+//
+// Data that cannot be parsed as tar headers, including anything after the
+// end-of-archive marker, is handed to the inner splitter unchanged.
+type TarSplitter struct {
+	r     io.Reader
+	trap  *readTrap
+	inner SplitterGen
+
+	cur         Splitter
+	passthrough bool
+
+	err error
+}
+
+// NewTarSplitter returns a TarSplitter which splits member payloads with
+// the given inner splitter.
+func NewTarSplitter(r io.Reader, inner SplitterGen) *TarSplitter {
+	return &TarSplitter{
+		r:     r,
+		trap:  newReadTrap(r),
+		inner: inner,
+	}
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (ts *TarSplitter) Reader() io.Reader {
+	return ts.r
+}
+
+// NextBytes produces a new chunk.
+func (ts *TarSplitter) NextBytes() ([]byte, error) {
+	return ts.trap.result(ts.nextBytes())
+}
+
+func (ts *TarSplitter) nextBytes() ([]byte, error) {
+	if ts.err != nil {
+		return nil, ts.err
+	}
+
+	for {
+		if ts.cur != nil {
+			b, err := ts.cur.NextBytes()
+			if err == nil {
+				return b, nil
+			} else if err != io.EOF || ts.passthrough {
+				ts.err = err
+				return nil, err
+			}
+			ts.cur = nil
+		}
+
+		block := make([]byte, tarBlockSize)
+		n, err := io.ReadFull(ts.trap, block)
+		switch err {
+		case nil:
+		case io.ErrUnexpectedEOF:
+			ts.err = io.EOF
+			return block[:n], nil
+		default:
+			ts.err = err
+			return nil, err
+		}
+
+		size, ok := parseTarHeader(block)
+		if !ok {
+			ts.passthrough = true
+			ts.cur = ts.inner(io.MultiReader(bytes.NewReader(block), ts.trap))
+			continue
+		}
+
+		if size > 0 {
+			padded := (size + tarBlockSize - 1) / tarBlockSize * tarBlockSize
+			ts.cur = ts.inner(io.LimitReader(ts.trap, padded))
+		}
+		return block, nil
+	}
+}
+
+// parseTarHeader validates a tar header block and returns the size of the
+// payload that follows it. It reports false for the end-of-archive marker
+// and for anything that is not a valid header.
+func parseTarHeader(block []byte) (int64, bool) {
+	chksum, ok := parseTarNumeric(block[148:156])
+	if !ok {
+		return 0, false
+	}
+
+	// The checksum is computed with the checksum field itself set to spaces.
+	// Historic implementations summed signed bytes, so accept both.
+	var unsigned, signed int64
+	for i, b := range block {
+		if i >= 148 && i < 156 {
+			b = ' '
+		}
+		unsigned += int64(b)
+		signed += int64(int8(b))
+	}
+	if chksum != unsigned && chksum != signed {
+		return 0, false
+	}
+
+	switch block[156] {
+	case '1', '2', '3', '4', '5', '6':
+		// Links, devices, directories and fifos carry no data.
+		return 0, true
+	}
+
+	size, ok := parseTarNumeric(block[124:136])
+	if !ok || size < 0 {
+		return 0, false
+	}
+	return size, true
+}
+
+// parseTarNumeric parses an octal or base-256 encoded tar header field.
+func parseTarNumeric(field []byte) (int64, bool) {
+	if len(field) > 0 && field[0]&0x80 != 0 {
+		// Base-256 (GNU) encoding, only positive values are meaningful here.
+		if field[0]&0x40 != 0 {
+			return 0, false
+		}
+		var v int64
+		for i, b := range field {
+			if i == 0 {
+				b &= 0x7f
+			}
+			if v > math.MaxInt64>>8 {
+				return 0, false
+			}
+			v = v<<8 | int64(b)
+		}
+		return v, true
+	}
+
+	s := strings.Trim(string(field), " \x00")
+	if s == "" {
+		return 0, true
+	}
+	v, err := strconv.ParseInt(s, 8, 64)
+	return v, err == nil
+}
@@ -0,0 +1,133 @@
+package chunk
+
+import (
+	"archive/tar"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type tarEntry struct {
+	name string
+	body []byte
+}
+
+func makeTar(t *testing.T, entries []tarEntry) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:     e.name,
+			Mode:     0644,
+			Size:     int64(len(e.body)),
+			Typeflag: tar.TypeReg,
+		}
+		if e.body == nil {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Mode = 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(e.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestTarSplitter(t *testing.T) {
+	shared := randBuf(t, 600<<10)
+	a := makeTar(t, []tarEntry{
+		{"dir/", nil},
+		{"small", []byte("hello")},
+		{"empty", []byte{}},
+		{"shared", shared},
+		{"dir/" + strings.Repeat("x", 200), randBuf(t, 1000)},
+	})
+	b := makeTar(t, []tarEntry{
+		{"other", randBuf(t, 777)},
+		{"shared", shared},
+	})
+
+	chunksA := splitAll(t, NewTarSplitter(bytes.NewReader(a), SizeSplitterGen(256<<10)))
+	chunksB := splitAll(t, NewTarSplitter(bytes.NewReader(b), SizeSplitterGen(256<<10)))
+
+	if !bytes.Equal(bytes.Join(chunksA, nil), a) || !bytes.Equal(bytes.Join(chunksB, nil), b) {
+		t.Fatal("data was chunked incorrectly")
+	}
+
+	// Every header must be a chunk of its own.
+	starts := make(map[int64]int)
+	var off int64
+	for _, chunk := range chunksA {
+		starts[off] = len(chunk)
+		off += int64(len(chunk))
+	}
+	var headers int
+	for off = 0; ; headers++ {
+		size, ok := parseTarHeader(a[off : off+tarBlockSize])
+		if !ok {
+			break
+		}
+		if starts[off] != tarBlockSize {
+			t.Fatalf("header at %d is not a chunk of its own", off)
+		}
+		off += tarBlockSize + (size+tarBlockSize-1)/tarBlockSize*tarBlockSize
+	}
+	// The long name needs an extra PAX header.
+	if headers != 6 {
+		t.Fatalf("expected 6 headers, found %d", headers)
+	}
+
+	seen := make(map[string]bool)
+	for _, chunk := range chunksA {
+		seen[string(chunk)] = true
+	}
+	var common int
+	for _, chunk := range chunksB {
+		if seen[string(chunk)] {
+			common += len(chunk)
+		}
+	}
+	if common < len(shared) {
+		t.Fatalf("expected the shared member to dedup, only %d bytes in common", common)
+	}
+}
+
+func TestTarSplitterInvalid(t *testing.T) {
+	data := randBuf(t, 1<<20)
+	chunks := splitAll(t, NewTarSplitter(bytes.NewReader(data), SizeSplitterGen(256<<10)))
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+
+	tarball := makeTar(t, []tarEntry{{"file", randBuf(t, 5000)}})
+	truncated := tarball[:1000]
+	chunks = splitAll(t, NewTarSplitter(bytes.NewReader(truncated), SizeSplitterGen(256<<10)))
+	if !bytes.Equal(bytes.Join(chunks, nil), truncated) {
+		t.Fatal("truncated data was chunked incorrectly")
+	}
+}
+
+func TestParseTarNumeric(t *testing.T) {
+	for _, tc := range []struct {
+		field string
+		v     int64
+		ok    bool
+	}{
+		{"00000001750\x00", 1000, true},
+		{"           \x00", 0, true},
+		{"\x80\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00\x00", 1 << 33, true},
+		{"\xff\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00\x00", 0, false},
+		{"garbage", 0, false},
+	} {
+		v, ok := parseTarNumeric([]byte(tc.field))
+		if v != tc.v || ok != tc.ok {
+			t.Errorf("%q: expected %d, %v, got %d, %v", tc.field, tc.v, tc.ok, v, ok)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package chunk
+
+import (
+	"io"
+	"math"
+	"sync"
+
+	"github.com/whyrusleeping/chunker"
+)
+
+// rabinWindow is the size of the sliding window used for Rabin
+// fingerprints, matching github.com/whyrusleeping/chunker.
+const rabinWindow = 16
+
+type rabinTables struct {
+	out      [256]uint64
+	mod      [256]uint64
+	polShift uint
+}
+
+var (
+	ipfsRabinTables     *rabinTables
+	ipfsRabinTablesOnce sync.Once
+)
+
+// newRabinTables precomputes the tables used to slide bytes out of the
+// window and to reduce modulo pol, in the same way the chunker library does.
+func newRabinTables(pol chunker.Pol) *rabinTables {
+	t := &rabinTables{polShift: uint(pol.Deg() - 8)}
+
+	appendByte := func(h chunker.Pol, b byte) chunker.Pol {
+		return (h<<8 | chunker.Pol(b)).Mod(pol)
+	}
+	for b := 0; b < 256; b++ {
+		h := appendByte(0, byte(b))
+		for i := 0; i < rabinWindow-1; i++ {
+			h = appendByte(h, 0)
+		}
+		t.out[b] = uint64(h)
+	}
+
+	k := uint(pol.Deg())
+	for b := 0; b < 256; b++ {
+		t.mod[b] = uint64(chunker.Pol(uint64(b)<<k).Mod(pol) | chunker.Pol(b)<<k)
+	}
+	return t
+}
+
+func (t *rabinTables) append(digest uint64, b byte) uint64 {
+	index := digest >> t.polShift
+	digest <<= 8
+	digest |= uint64(b)
+	return digest ^ t.mod[index]
+}
+
+// rabinTTTD finds Rabin fingerprint boundaries like the chunker library,
+// optionally with the Two-Threshold Two-Divisor algorithm used by
+// NewRabinTTTD and NewBuzhashTTTD: when no boundary is found before max,
+// the chunk ends at the last position that satisfies a secondary divisor
+// half as selective as the main one, and is only cut at max when there is
+// no such position.
+type rabinTTTD struct {
+	r   io.Reader
+	buf []byte
+	n   int
+
+	t          *rabinTables
+	min, max   int
+	mask       uint64
+	backupMask uint64
+	tttd       bool
+
+	err error
+}
+
+func newRabinTTTD(r io.Reader, min, avg, max uint64, tttd bool) *rabinTTTD {
+	ipfsRabinTablesOnce.Do(func() {
+		ipfsRabinTables = newRabinTables(IpfsRabinPoly)
+	})
+
+	sizepow := uint(math.Log2(float64(avg)))
+	return &rabinTTTD{
+		r:          r,
+		buf:        getBuf(int(max)),
+		t:          ipfsRabinTables,
+		min:        int(min),
+		max:        int(max),
+		mask:       1<<sizepow - 1,
+		backupMask: 1<<(sizepow-1) - 1,
+		tttd:       tttd,
+	}
+}
+
+// NewRabinTTTD returns a new Rabin splitter which uses the given min,
+// average and max block sizes, and the Two-Threshold Two-Divisor algorithm.
+// min must be at least 16 bytes, avg must lie between min and max, and max
+// may not exceed ChunkSizeLimit.
+func NewRabinTTTD(r io.Reader, min, avg, max uint64) (*Rabin, error) {
+	switch {
+	case min < rabinWindow:
+		return nil, ErrRabinMin
+	case avg < min || avg > max:
+		return nil, ErrRabinAvg
+	case max > uint64(ChunkSizeLimit):
+		return nil, ErrSizeMax
+	}
+
+	trap := newReadTrap(r)
+	return &Rabin{
+		tttd:   newRabinTTTD(trap, min, avg, max, true),
+		reader: r,
+		trap:   trap,
+	}, nil
+}
+
+// NextBytes produces a new chunk.
+func (rt *rabinTTTD) NextBytes() ([]byte, error) {
+	if rt.err != nil {
+		return nil, rt.err
+	}
+
+	n, err := io.ReadFull(rt.r, rt.buf[rt.n:])
+	buffered := rt.n + n
+	if err != nil {
+		if err != io.ErrUnexpectedEOF && err != io.EOF {
+			rt.err = err
+			putBuf(rt.buf)
+			rt.buf = nil
+			return nil, err
+		}
+		if buffered <= rt.min {
+			rt.err = io.EOF
+			// Read nothing? Don't return an empty block.
+			if buffered == 0 {
+				putBuf(rt.buf)
+				rt.buf = nil
+				return nil, rt.err
+			}
+			res := make([]byte, buffered)
+			copy(res, rt.buf)
+
+			putBuf(rt.buf)
+			rt.buf = nil
+			return res, nil
+		}
+	}
+
+	i := rt.boundary(rt.buf[:buffered])
+
+	res := make([]byte, i)
+	copy(res, rt.buf)
+
+	rt.n = copy(rt.buf, rt.buf[i:buffered])
+
+	return res, nil
+}
+
+// boundary returns the length of the chunk at the start of buf, which holds
+// more than min bytes.
+func (rt *rabinTTTD) boundary(buf []byte) int {
+	var digest uint64
+	for _, b := range buf[rt.min-rabinWindow : rt.min] {
+		digest = rt.t.append(digest, b)
+	}
+
+	backup := 0
+	for i := rt.min; ; i++ {
+		if digest&rt.mask == 0 {
+			return i
+		}
+		if i >= rt.max {
+			if backup > 0 {
+				return backup
+			}
+			return i
+		}
+		if rt.tttd && digest&rt.backupMask == 0 {
+			backup = i
+		}
+		if i == len(buf) {
+			return i
+		}
+
+		digest ^= rt.t.out[buf[i-rabinWindow]]
+		digest = rt.t.append(digest, buf[i])
+	}
+}
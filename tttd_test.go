@@ -0,0 +1,141 @@
+package chunk
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func splitAll(t *testing.T, s Splitter) [][]byte {
+	var chunks [][]byte
+	for {
+		chunk, err := s.NextBytes()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+func countSize(chunks [][]byte, size int) (count int) {
+	for _, chunk := range chunks {
+		if len(chunk) == size {
+			count++
+		}
+	}
+	return count
+}
+
+func TestRabinFingerprintMatchesLibrary(t *testing.T) {
+	data := randBuf(t, 4<<20)
+	const min, avg, max = 1024, 8192, 12288
+
+	want := splitAll(t, NewRabinMinMax(bytes.NewReader(data), min, avg, max))
+	trap := newReadTrap(bytes.NewReader(data))
+	got := splitAll(t, &Rabin{tttd: newRabinTTTD(trap, min, avg, max, false), trap: trap})
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %d chunks, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Fatalf("chunk %d differs from the chunker library", i)
+		}
+	}
+}
+
+func TestRabinTTTD(t *testing.T) {
+	data := randBuf(t, 4<<20)
+	const min, avg, max = 1024, 8192, 12288
+
+	plain := splitAll(t, NewRabinMinMax(bytes.NewReader(data), min, avg, max))
+	r, err := NewRabinTTTD(bytes.NewReader(data), min, avg, max)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := splitAll(t, r)
+
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > max {
+			t.Fatalf("chunk %d/%d is larger than the maximum size", i+1, len(chunks))
+		}
+		if i < len(chunks)-1 && len(chunk) < min {
+			t.Fatalf("chunk %d/%d is less than the minimum size", i+1, len(chunks))
+		}
+	}
+
+	forced, forcedTTTD := countSize(plain, max), countSize(chunks, max)
+	t.Logf("forced cuts: %d without TTTD, %d with", forced, forcedTTTD)
+	if forcedTTTD >= forced {
+		t.Fatal("TTTD did not reduce the number of forced cuts")
+	}
+}
+
+func TestRabinTTTDInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		min, avg, max uint64
+		err           error
+	}{
+		{8, 64, 256, ErrRabinMin},
+		{0, 0, 0, ErrRabinMin},
+		{16, 8, 256, ErrRabinAvg},
+		{16, 512, 256, ErrRabinAvg},
+		{16, 64, uint64(ChunkSizeLimit) + 1, ErrSizeMax},
+	} {
+		if _, err := NewRabinTTTD(bytes.NewReader(nil), tc.min, tc.avg, tc.max); err != tc.err {
+			t.Fatalf("%d-%d-%d: expected %v, got %v", tc.min, tc.avg, tc.max, tc.err, err)
+		}
+	}
+}
+
+// mustRabinTTTD is NewRabinTTTD for sizes known to be valid.
+func mustRabinTTTD(r io.Reader, min, avg, max uint64) *Rabin {
+	s, err := NewRabinTTTD(r, min, avg, max)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func TestBuzhashTTTD(t *testing.T) {
+	data := randBuf(t, 16<<20)
+
+	plain := splitAll(t, NewBuzhash(bytes.NewReader(data)))
+	chunks := splitAll(t, NewBuzhashTTTD(bytes.NewReader(data)))
+
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+	for i, chunk := range chunks[:len(chunks)-1] {
+		if len(chunk) < buzMin || len(chunk) > buzMax {
+			t.Fatalf("chunk %d/%d has invalid size %d", i+1, len(chunks), len(chunk))
+		}
+	}
+
+	forced, forcedTTTD := countSize(plain, buzMax), countSize(chunks, buzMax)
+	t.Logf("forced cuts: %d without TTTD, %d with", forced, forcedTTTD)
+	if forcedTTTD > forced {
+		t.Fatal("TTTD increased the number of forced cuts")
+	}
+}
+
+func TestRabinTTTDChunkReuse(t *testing.T) {
+	newRabin := func(r io.Reader) Splitter {
+		return mustRabinTTTD(r, 256*1024/3, 256*1024, 256*1024*3/2)
+	}
+	testReuse(t, newRabin)
+}
+
+func TestBuzhashTTTDChunkReuse(t *testing.T) {
+	newBuzhash := func(r io.Reader) Splitter {
+		return NewBuzhashTTTD(r)
+	}
+	testReuse(t, newBuzhash)
+}
@@ -0,0 +1,149 @@
+package chunk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// ErrNoCandidate is returned by Tune when no candidate spec satisfies the
+// constraint of the objective.
+var ErrNoCandidate = errors.New("no chunker spec satisfies the tuning constraint")
+
+// TuneObjective selects what Tune optimizes for.
+type TuneObjective int
+
+const (
+	// MaximizeDedup picks the spec with the best dedup ratio among those
+	// whose average chunk size is at least TuneOptions.MinAvgSize.
+	MaximizeDedup TuneObjective = iota
+	// MinimizeBlocks picks the spec storing the fewest unique blocks among
+	// those whose dedup ratio is at least TuneOptions.MinRatio.
+	MinimizeBlocks
+)
+
+// TuneOptions configures Tune.
+type TuneOptions struct {
+	Objective  TuneObjective
+	MinAvgSize int
+	MinRatio   float64
+
+	// Families lists the chunker families to search, "rabin" and/or
+	// "casync" (the buzhash based splitter with configurable sizes). Both
+	// are searched when empty.
+	Families []string
+	// Avgs lists the average chunk sizes to try. Powers of two from 4KiB
+	// to 256KiB are tried when empty.
+	Avgs []int
+}
+
+// TuneResult is the outcome of chunking the corpus with one spec.
+type TuneResult struct {
+	Spec  string
+	Stats DedupStats
+}
+
+var defaultTuneAvgs = []int{4 << 10, 8 << 10, 16 << 10, 32 << 10, 64 << 10, 128 << 10, 256 << 10}
+
+// TuneCandidates returns the specs searched by Tune for the given options:
+// for every family and average size, min is avg/4 or avg/2 and max is 2*avg
+// or 4*avg, as long as max does not exceed ChunkSizeLimit.
+func TuneCandidates(opts TuneOptions) []string {
+	families := opts.Families
+	if len(families) == 0 {
+		families = []string{"rabin", "casync"}
+	}
+	avgs := opts.Avgs
+	if len(avgs) == 0 {
+		avgs = defaultTuneAvgs
+	}
+
+	var specs []string
+	for _, family := range families {
+		for _, avg := range avgs {
+			for _, min := range []int{avg / 4, avg / 2} {
+				for _, max := range []int{avg * 2, avg * 4} {
+					if max > ChunkSizeLimit {
+						continue
+					}
+					specs = append(specs, fmt.Sprintf("%s-%d-%d-%d", family, min, avg, max))
+				}
+			}
+		}
+	}
+	return specs
+}
+
+// Tune chunks the samples with every candidate spec and returns the
+// results satisfying the objective's constraint, best first. The samples
+// are treated as one corpus, so passing several versions of the same data
+// measures how well they deduplicate against each other.
+func Tune(samples [][]byte, opts TuneOptions) ([]TuneResult, error) {
+	specs := TuneCandidates(opts)
+	results := make([]TuneResult, len(specs))
+	errs := make([]error, len(specs))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for i, spec := range specs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, spec string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			dc := NewDedupCounter(spec)
+			for _, s := range samples {
+				if err := dc.Add(bytes.NewReader(s)); err != nil {
+					errs[i] = fmt.Errorf("%s: %w", spec, err)
+					return
+				}
+			}
+			results[i] = TuneResult{Spec: spec, Stats: dc.Stats()}
+		}(i, spec)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var ok []TuneResult
+	for _, r := range results {
+		switch opts.Objective {
+		case MaximizeDedup:
+			if r.Stats.AvgChunkSize() < float64(opts.MinAvgSize) {
+				continue
+			}
+		case MinimizeBlocks:
+			if r.Stats.Ratio() < opts.MinRatio {
+				continue
+			}
+		default:
+			return nil, fmt.Errorf("unknown tuning objective %d", opts.Objective)
+		}
+		ok = append(ok, r)
+	}
+	if len(ok) == 0 {
+		return nil, ErrNoCandidate
+	}
+
+	sort.SliceStable(ok, func(i, j int) bool {
+		a, b := ok[i].Stats, ok[j].Stats
+		if opts.Objective == MinimizeBlocks && a.UniqueChunks != b.UniqueChunks {
+			return a.UniqueChunks < b.UniqueChunks
+		}
+		if a.UniqueBytes != b.UniqueBytes {
+			return a.UniqueBytes < b.UniqueBytes
+		}
+		return a.UniqueChunks < b.UniqueChunks
+	})
+	return ok, nil
+}
@@ -0,0 +1,81 @@
+package chunk
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTuneCandidates(t *testing.T) {
+	specs := TuneCandidates(TuneOptions{})
+	if len(specs) != 2*7*4 {
+		t.Fatalf("unexpected number of candidates: %d", len(specs))
+	}
+	for _, spec := range specs {
+		if _, err := FromString(bytes.NewReader(nil), spec); err != nil {
+			t.Fatalf("%s: %s", spec, err)
+		}
+	}
+}
+
+func tuneCorpus(t *testing.T) [][]byte {
+	v1 := randBuf(t, 4<<20)
+	v2 := append([]byte(nil), v1[:1<<20]...)
+	v2 = append(v2, randBuf(t, 1000)...)
+	v2 = append(v2, v1[1<<20:]...)
+	return [][]byte{v1, v2}
+}
+
+func TestTuneMaximizeDedup(t *testing.T) {
+	results, err := Tune(tuneCorpus(t), TuneOptions{
+		Objective:  MaximizeDedup,
+		MinAvgSize: 32 << 10,
+		Avgs:       []int{16 << 10, 64 << 10, 256 << 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, r := range results {
+		if r.Stats.AvgChunkSize() < 32<<10 {
+			t.Fatalf("%s does not satisfy the constraint: %+v", r.Spec, r.Stats)
+		}
+		if i > 0 && r.Stats.Ratio() > results[i-1].Stats.Ratio() {
+			t.Fatalf("results are not sorted by ratio: %s before %s", results[i-1].Spec, r.Spec)
+		}
+	}
+	if r := results[0].Stats.Ratio(); r < 1.8 {
+		t.Fatalf("best spec %s only reached a ratio of %f", results[0].Spec, r)
+	}
+}
+
+func TestTuneMinimizeBlocks(t *testing.T) {
+	results, err := Tune(tuneCorpus(t), TuneOptions{
+		Objective: MinimizeBlocks,
+		MinRatio:  1.5,
+		Families:  []string{"casync"},
+		Avgs:      []int{16 << 10, 64 << 10, 256 << 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, r := range results {
+		if r.Stats.Ratio() < 1.5 {
+			t.Fatalf("%s does not satisfy the constraint: %+v", r.Spec, r.Stats)
+		}
+		if i > 0 && r.Stats.UniqueChunks < results[i-1].Stats.UniqueChunks {
+			t.Fatalf("results are not sorted by block count: %s before %s", results[i-1].Spec, r.Spec)
+		}
+	}
+}
+
+func TestTuneNoCandidate(t *testing.T) {
+	_, err := Tune(tuneCorpus(t), TuneOptions{
+		Objective: MinimizeBlocks,
+		MinRatio:  10,
+		Avgs:      []int{64 << 10},
+	})
+	if err != ErrNoCandidate {
+		t.Fatalf("expected ErrNoCandidate, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package chunk
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// VarintFramedSplitter implements the Splitter interface for streams of
+// records prefixed with their length as an unsigned varint, such as
+// length-delimited protobuf messages or the sections of a CARv1 file.
+// Whole records, including their length prefix, are grouped into chunks of
+// up to the target size. A record larger than the target size makes up a
+// chunk of its own, and is only split when it alone exceeds ChunkSizeLimit.
+//
+// If the stream stops looking like varint framed records, the rest of it
+// is cut into chunks of ChunkSizeLimit bytes.
+type VarintFramedSplitter struct {
+	r    io.Reader
+	trap *readTrap
+	br   *bufio.Reader
+	size int
+
+	// oversize is the number of bytes left of a record which is being cut
+	// into chunks of ChunkSizeLimit.
+	oversize int64
+	raw      Splitter
+
+	err error
+}
+
+// NewVarintFramedSplitter returns a VarintFramedSplitter producing chunks of
+// up to the given size, which must be positive and may not exceed
+// ChunkSizeLimit.
+func NewVarintFramedSplitter(r io.Reader, size int64) (*VarintFramedSplitter, error) {
+	if size <= 0 {
+		return nil, ErrSize
+	} else if size > int64(ChunkSizeLimit) {
+		return nil, ErrSizeMax
+	}
+
+	trap := newReadTrap(r)
+	return &VarintFramedSplitter{
+		r:    r,
+		trap: trap,
+		br:   bufio.NewReader(trap),
+		size: int(size),
+	}, nil
+}
+
+// Reader returns the io.Reader associated to this Splitter.
+func (vs *VarintFramedSplitter) Reader() io.Reader {
+	return vs.r
+}
+
+// NextBytes produces a new chunk.
+func (vs *VarintFramedSplitter) NextBytes() ([]byte, error) {
+	return vs.trap.result(vs.nextBytes())
+}
+
+func (vs *VarintFramedSplitter) nextBytes() ([]byte, error) {
+	if vs.err != nil {
+		return nil, vs.err
+	}
+	if vs.raw != nil {
+		return vs.raw.NextBytes()
+	}
+	if vs.oversize > 0 {
+		n := int64(ChunkSizeLimit)
+		if vs.oversize < n {
+			n = vs.oversize
+		}
+		vs.oversize -= n
+		return vs.read(nil, int(n))
+	}
+
+	var buf []byte
+	for len(buf) < vs.size {
+		prefix, err := vs.br.Peek(binary.MaxVarintLen64)
+		if len(prefix) == 0 {
+			if err == nil || err == io.EOF {
+				break
+			}
+			vs.err = err
+			return nil, err
+		}
+
+		length, n := binary.Uvarint(prefix)
+		if n <= 0 || length > uint64(1<<62) {
+			vs.raw = NewSizeSplitter(vs.br, int64(ChunkSizeLimit))
+			if len(buf) > 0 {
+				return buf, nil
+			}
+			return vs.raw.NextBytes()
+		}
+
+		total := int64(n) + int64(length)
+		if len(buf) > 0 && int64(len(buf))+total > int64(vs.size) {
+			return buf, nil
+		}
+		if total > int64(ChunkSizeLimit) {
+			vs.oversize = total
+			return vs.NextBytes()
+		}
+
+		buf, err = vs.read(buf, int(total))
+		if err != nil || vs.err != nil {
+			return buf, err
+		}
+	}
+
+	if len(buf) == 0 {
+		vs.err = io.EOF
+		return nil, vs.err
+	}
+	return buf, nil
+}
+
+// read appends n bytes to buf. If the stream ends early, what could be read
+// is returned and the splitter is done.
+func (vs *VarintFramedSplitter) read(buf []byte, n int) ([]byte, error) {
+	start := len(buf)
+	if cap(buf)-start < n {
+		grown := make([]byte, start, start+n)
+		copy(grown, buf)
+		buf = grown
+	}
+	buf = buf[:start+n]
+
+	read, err := io.ReadFull(vs.br, buf[start:])
+	switch err {
+	case nil:
+		return buf, nil
+	case io.ErrUnexpectedEOF, io.EOF:
+		vs.err = io.EOF
+		buf = buf[:start+read]
+		if len(buf) == 0 {
+			return nil, io.EOF
+		}
+		return buf, nil
+	default:
+		vs.err = err
+		return nil, err
+	}
+}
@@ -0,0 +1,90 @@
+package chunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func mustVarintFramed(r io.Reader, size int64) *VarintFramedSplitter {
+	s, err := NewVarintFramedSplitter(r, size)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func varintRecords(t *testing.T, sizes ...int) ([]byte, []int) {
+	var buf []byte
+	var ends []int
+	for _, size := range sizes {
+		buf = binary.AppendUvarint(buf, uint64(size))
+		buf = append(buf, randBuf(t, size)...)
+		ends = append(ends, len(buf))
+	}
+	return buf, ends
+}
+
+func TestVarintFramedSplitter(t *testing.T) {
+	sizes := []int{10, 100, 1000, 3000, 50, 5000, 20, 20, 20, ChunkSizeLimit + 10, 7}
+	data, ends := varintRecords(t, sizes...)
+
+	chunks := splitAll(t, mustVarintFramed(bytes.NewReader(data), 4096))
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+
+	isEnd := make(map[int]bool)
+	for _, end := range ends {
+		isEnd[end] = true
+	}
+	var off int
+	for i, chunk := range chunks {
+		off += len(chunk)
+		if len(chunk) > ChunkSizeLimit {
+			t.Fatalf("chunk %d/%d exceeds ChunkSizeLimit", i+1, len(chunks))
+		}
+		// Only the record larger than ChunkSizeLimit may be split.
+		if !isEnd[off] && len(chunk) != ChunkSizeLimit {
+			t.Fatalf("chunk %d/%d does not end on a record boundary", i+1, len(chunks))
+		}
+	}
+
+	want := "[1114 3053 5002 63 1048576 13 8]"
+	if got := fmt.Sprint(chunkSizes(chunks)); got != want {
+		t.Fatalf("expected chunk sizes %s, got %s", want, got)
+	}
+}
+
+func TestVarintFramedSplitterInvalid(t *testing.T) {
+	good, _ := varintRecords(t, 100, 200)
+
+	for name, data := range map[string][]byte{
+		"overflow":  append(append([]byte{}, good...), bytes.Repeat([]byte{0xff}, 20)...),
+		"truncated": append(append([]byte{}, good...), 0x80, 0x01, 1, 2, 3),
+		"random":    randBuf(t, 3*ChunkSizeLimit),
+	} {
+		chunks := splitAll(t, mustVarintFramed(bytes.NewReader(data), 4096))
+		if !bytes.Equal(bytes.Join(chunks, nil), data) {
+			t.Fatalf("%s: data was chunked incorrectly", name)
+		}
+		for i, chunk := range chunks {
+			if len(chunk) == 0 || len(chunk) > ChunkSizeLimit {
+				t.Fatalf("%s: chunk %d/%d has invalid size %d", name, i+1, len(chunks), len(chunk))
+			}
+		}
+	}
+}
+
+func TestVarintFramedSplitterSize(t *testing.T) {
+	for _, size := range []int64{0, -1} {
+		if _, err := NewVarintFramedSplitter(bytes.NewReader([]byte{1, 2}), size); err != ErrSize {
+			t.Fatalf("size %d: expected ErrSize, got %v", size, err)
+		}
+	}
+	if _, err := NewVarintFramedSplitter(bytes.NewReader(nil), int64(ChunkSizeLimit)+1); err != ErrSizeMax {
+		t.Fatalf("expected ErrSizeMax, got %v", err)
+	}
+}
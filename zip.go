@@ -0,0 +1,213 @@
+package chunk
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	zipLocalHeaderSig    = 0x04034b50
+	zipCentralHeaderSig  = 0x02014b50
+	zipEndSig            = 0x06054b50
+	zipEnd64LocatorSig   = 0x07064b50
+	zipEnd64Sig          = 0x06064b50
+	zipLocalHeaderLen    = 30
+	zipCentralHeaderLen  = 46
+	zipEndLen            = 22
+	zipEnd64LocatorLen   = 20
+	zipEnd64Len          = 56
+	zipMaxCommentLen     = 65535
+	zipExtraZip64        = 0x0001
+	zipMaxCentralEntries = 1 << 24
+)
+
+// ErrNotZip is returned by NewZipSplitter when the input has no valid ZIP
+// central directory.
+var ErrNotZip = errors.New("not a zip archive")
+
+// ZipSplitter implements the Splitter interface for ZIP archives (including
+// JAR, DOCX, APK and the like). It reads the central directory to find every
+// entry and cuts at each local file header and at the start of each entry's
+// data, so that identical entries stored in different archives produce the
+// same chunks. Entry data, as well as anything between or around the
+// entries, is split by an inner splitter.
+type ZipSplitter struct {
+	sectionSplitter
+}
+
+// NewZipSplitter returns a ZipSplitter for the archive of the given size
+// in r, which splits entries with the given inner splitter.
+func NewZipSplitter(r io.ReaderAt, size int64, inner SplitterGen) (*ZipSplitter, error) {
+	offsets, dirOff, err := zipLocalHeaderOffsets(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	cuts := []int64{dirOff}
+	for _, off := range offsets {
+		dataOff, err := zipDataOffset(r, off)
+		if err != nil {
+			return nil, err
+		} else if dataOff > size {
+			return nil, ErrNotZip
+		}
+		cuts = append(cuts, off, dataOff)
+	}
+
+	return &ZipSplitter{newSectionSplitter(r, size, inner, cuts)}, nil
+}
+
+// zipLocalHeaderOffsets returns the offsets of all local file headers listed
+// in the central directory, and the offset of the central directory itself.
+func zipLocalHeaderOffsets(r io.ReaderAt, size int64) ([]int64, int64, error) {
+	le := binary.LittleEndian
+
+	// Find the end of central directory record, which may be followed by a
+	// comment of up to 64KiB.
+	tailLen := int64(zipEndLen + zipMaxCommentLen)
+	if tailLen > size {
+		tailLen = size
+	}
+	tail := make([]byte, tailLen)
+	if _, err := r.ReadAt(tail, size-tailLen); err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+	end := -1
+	for i := len(tail) - zipEndLen; i >= 0; i-- {
+		if le.Uint32(tail[i:]) == zipEndSig && i+zipEndLen+int(le.Uint16(tail[i+20:])) <= len(tail) {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, 0, ErrNotZip
+	}
+	endOff := size - tailLen + int64(end)
+
+	count := uint64(le.Uint16(tail[end+10:]))
+	dirSize := uint64(le.Uint32(tail[end+12:]))
+	dirOff := uint64(le.Uint32(tail[end+16:]))
+
+	if count == 0xffff || dirSize == 0xffffffff || dirOff == 0xffffffff {
+		var err error
+		count, dirSize, dirOff, err = zip64End(r, endOff)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if dirSize > uint64(size) || dirOff > uint64(size)-dirSize || count > zipMaxCentralEntries {
+		return nil, 0, ErrNotZip
+	}
+	// Do not trust the entry count to size allocations, the directory must
+	// actually hold that many headers.
+	if max := dirSize / zipCentralHeaderLen; count > max {
+		count = max
+	}
+
+	dir := make([]byte, dirSize)
+	if _, err := r.ReadAt(dir, int64(dirOff)); err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+
+	offsets := make([]int64, 0, count)
+	for len(dir) >= zipCentralHeaderLen && le.Uint32(dir) == zipCentralHeaderSig {
+		nameLen := int(le.Uint16(dir[28:]))
+		extraLen := int(le.Uint16(dir[30:]))
+		commentLen := int(le.Uint16(dir[32:]))
+		entryLen := zipCentralHeaderLen + nameLen + extraLen + commentLen
+		if entryLen > len(dir) {
+			return nil, 0, ErrNotZip
+		}
+
+		off := uint64(le.Uint32(dir[42:]))
+		if off == 0xffffffff {
+			// The zip64 extra field lists the values that overflowed in a
+			// fixed order; the header offset comes after the two sizes.
+			extra := dir[zipCentralHeaderLen+nameLen : zipCentralHeaderLen+nameLen+extraLen]
+			skip := 0
+			if le.Uint32(dir[24:]) == 0xffffffff {
+				skip += 8
+			}
+			if le.Uint32(dir[20:]) == 0xffffffff {
+				skip += 8
+			}
+			var ok bool
+			off, ok = zip64Field(extra, skip)
+			if !ok {
+				return nil, 0, ErrNotZip
+			}
+		}
+		if off >= dirOff {
+			return nil, 0, ErrNotZip
+		}
+		offsets = append(offsets, int64(off))
+		dir = dir[entryLen:]
+	}
+
+	return offsets, int64(dirOff), nil
+}
+
+// zip64End reads the zip64 end of central directory record.
+func zip64End(r io.ReaderAt, endOff int64) (count, dirSize, dirOff uint64, err error) {
+	le := binary.LittleEndian
+
+	if endOff < zipEnd64LocatorLen {
+		return 0, 0, 0, ErrNotZip
+	}
+	loc := make([]byte, zipEnd64LocatorLen)
+	if _, err := r.ReadAt(loc, endOff-zipEnd64LocatorLen); err != nil {
+		return 0, 0, 0, err
+	}
+	if le.Uint32(loc) != zipEnd64LocatorSig {
+		return 0, 0, 0, ErrNotZip
+	}
+
+	rec := make([]byte, zipEnd64Len)
+	if _, err := r.ReadAt(rec, int64(le.Uint64(loc[8:]))); err != nil {
+		return 0, 0, 0, err
+	}
+	if le.Uint32(rec) != zipEnd64Sig {
+		return 0, 0, 0, ErrNotZip
+	}
+	return le.Uint64(rec[32:]), le.Uint64(rec[40:]), le.Uint64(rec[48:]), nil
+}
+
+// zip64Field returns the 8 byte value at the given position in the zip64
+// extended information extra field.
+func zip64Field(extra []byte, pos int) (uint64, bool) {
+	le := binary.LittleEndian
+	for len(extra) >= 4 {
+		id := le.Uint16(extra)
+		n := int(le.Uint16(extra[2:]))
+		if 4+n > len(extra) {
+			return 0, false
+		}
+		if id == zipExtraZip64 {
+			if pos+8 > n {
+				return 0, false
+			}
+			return le.Uint64(extra[4+pos:]), true
+		}
+		extra = extra[4+n:]
+	}
+	return 0, false
+}
+
+// zipDataOffset returns the offset of the entry data following the local
+// file header at off.
+func zipDataOffset(r io.ReaderAt, off int64) (int64, error) {
+	le := binary.LittleEndian
+
+	hdr := make([]byte, zipLocalHeaderLen)
+	if _, err := r.ReadAt(hdr, off); err != nil {
+		if err == io.EOF {
+			return 0, ErrNotZip
+		}
+		return 0, err
+	}
+	if le.Uint32(hdr) != zipLocalHeaderSig {
+		return 0, ErrNotZip
+	}
+	return off + zipLocalHeaderLen + int64(le.Uint16(hdr[26:])) + int64(le.Uint16(hdr[28:])), nil
+}
@@ -0,0 +1,135 @@
+package chunk
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"runtime"
+	"testing"
+)
+
+func makeZip(t *testing.T, entries []tarEntry, comment string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(e.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.SetComment(comment); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestZipSplitter(t *testing.T) {
+	shared := randBuf(t, 300<<10)
+	a := makeZip(t, []tarEntry{
+		{"a.txt", []byte("hello")},
+		{"empty", nil},
+		{"shared.bin", shared},
+	}, "archive comment")
+	b := makeZip(t, []tarEntry{
+		{"other", randBuf(t, 12345)},
+		{"shared.bin", shared},
+	}, "")
+
+	za, err := zip.NewReader(bytes.NewReader(a), int64(len(a)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewZipSplitter(bytes.NewReader(a), int64(len(a)), SizeSplitterGen(64<<10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunksA := splitAll(t, s)
+	if !bytes.Equal(bytes.Join(chunksA, nil), a) {
+		t.Fatal("data was chunked incorrectly")
+	}
+
+	starts := chunkStarts(chunksA)
+	for _, f := range za.File {
+		off, err := f.DataOffset()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !starts[int(off)] {
+			t.Fatalf("data of %s at %d does not start a chunk", f.Name, off)
+		}
+	}
+
+	s, err = NewZipSplitter(bytes.NewReader(b), int64(len(b)), SizeSplitterGen(64<<10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, chunk := range chunksA {
+		seen[string(chunk)] = true
+	}
+	var common int
+	for _, chunk := range splitAll(t, s) {
+		if seen[string(chunk)] {
+			common += len(chunk)
+		}
+	}
+	if common < len(shared) {
+		t.Fatalf("expected the shared entry to dedup, only %d bytes in common", common)
+	}
+}
+
+func TestZipSplitterFromString(t *testing.T) {
+	data := makeZip(t, []tarEntry{{"a", randBuf(t, 1000)}}, "")
+
+	s, err := FromString(bytes.NewReader(data), "zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bytes.Join(splitAll(t, s), nil), data) {
+		t.Fatal("data was chunked incorrectly")
+	}
+
+	if _, err := FromString(io.LimitReader(bytes.NewReader(data), 100), "zip"); err != ErrNotSeekable {
+		t.Fatalf("Expected 'ErrNotSeekable', got: %#v", err)
+	}
+	if _, err := FromString(bytes.NewReader(randBuf(t, 1000)), "zip"); err != ErrNotZip {
+		t.Fatalf("Expected 'ErrNotZip', got: %#v", err)
+	}
+}
+
+func TestZipSplitterEntryCount(t *testing.T) {
+	le := binary.LittleEndian
+
+	// An empty zip64 archive claiming the maximum number of entries.
+	var data []byte
+	rec := make([]byte, zipEnd64Len)
+	le.PutUint32(rec, zipEnd64Sig)
+	le.PutUint64(rec[32:], zipMaxCentralEntries)
+	data = append(data, rec...)
+	loc := make([]byte, zipEnd64LocatorLen)
+	le.PutUint32(loc, zipEnd64LocatorSig)
+	data = append(data, loc...)
+	end := make([]byte, zipEndLen)
+	le.PutUint32(end, zipEndSig)
+	le.PutUint16(end[10:], 0xffff)
+	le.PutUint32(end[16:], 0xffffffff)
+	data = append(data, end...)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := NewZipSplitter(bytes.NewReader(data), int64(len(data)), DefaultSplitter); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Fatalf("allocated %d bytes for a %d byte archive", n, len(data))
+	}
+}